@@ -1,37 +1,86 @@
 package app
 
 import (
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/backup/backup_repositories"
 	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
 	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/notification/notification_repositories"
+	"github.com/mooncorn/gshub-main-api/payment/payment_providers"
+	"github.com/mooncorn/gshub-main-api/payment/payment_repositories"
 	"github.com/mooncorn/gshub-main-api/plan/plan_repositories"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_repositories"
 	"github.com/mooncorn/gshub-main-api/service/service_repositories"
 	"github.com/mooncorn/gshub-main-api/user/user_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_repositories"
 
 	"gorm.io/gorm"
 )
 
 type Context struct {
-	DB                             *gorm.DB
-	InstanceClient                 *instance_aws.AWSClient
-	UserRepository                 *user_repositories.UserRepository
-	ServiceRepository              *service_repositories.ServiceRepository
-	PlanRepository                 *plan_repositories.PlanRepository
-	InstanceRepository             *instance_repositories.InstanceRepository
-	InstanceCyclesRepository       *instance_repositories.InstanceCyclesRepository
-	InstanceBurnedCyclesRepository *instance_repositories.InstanceBurnedCyclesRepository
+	DB                            *gorm.DB
+	InstanceClient                instance_aws.InstanceClient
+	InstanceCache                 *instance_aws.InstanceCache
+	Events                        *instance_events.Hub
+	UserRepository                *user_repositories.UserRepository
+	ServiceRepository             *service_repositories.ServiceRepository
+	PlanRepository                *plan_repositories.PlanRepository
+	InstanceRepository            *instance_repositories.InstanceRepository
+	InstanceCredentialsRepository *instance_repositories.InstanceCredentialsRepository
+	InstanceMemberRepository      *instance_repositories.InstanceMemberRepository
+	ReconcileReportRepository     *instance_repositories.ReconcileReportRepository
+	LedgerRepository              *ledger_repositories.LedgerRepository
+	PurchaseRepository            *payment_repositories.PurchaseRepository
+	PaymentProvider               payment_providers.PaymentProvider
+	NotificationRepository        *notification_repositories.NotificationRepository
+	ScheduleRepository            *schedule_repositories.ScheduleRepository
+	WalletRepository              *wallet_repositories.WalletRepository
+	BackupRepository              *backup_repositories.BackupRepository
 }
 
 func NewContext(dbInstance *gorm.DB) *Context {
+	instanceClient := instance_aws.NewInstanceClient()
+	events := instance_events.NewHub()
+
+	// Every status change is published to the owner's event stream
+	instanceRepository := instance_repositories.NewInstanceRepository(dbInstance)
+	instanceRepository.Events = events
+
+	// Members of an instance receive its events too
+	instanceMemberRepository := instance_repositories.NewInstanceMemberRepository(dbInstance)
+	events.Audience = func(instanceID uint) []uint {
+		userIDs, err := instanceMemberRepository.GetMemberUserIDs(instanceID)
+		if err != nil {
+			log.Printf("Failed to get members of instance %d for events: %v", instanceID, err)
+		}
+		return userIDs
+	}
+
 	return &Context{
-		DB:                             dbInstance,
-		InstanceClient:                 instance_aws.NewAWSClient(),
-		UserRepository:                 user_repositories.NewUserRepository(dbInstance),
-		ServiceRepository:              service_repositories.NewServiceRepository(dbInstance),
-		PlanRepository:                 plan_repositories.NewPlanRepository(dbInstance),
-		InstanceRepository:             instance_repositories.NewInstanceRepository(dbInstance),
-		InstanceCyclesRepository:       instance_repositories.NewInstanceCyclesRepository(dbInstance),
-		InstanceBurnedCyclesRepository: instance_repositories.NewInstanceBurnedCyclesRepository(dbInstance),
+		DB:                            dbInstance,
+		InstanceClient:                instanceClient,
+		InstanceCache:                 instance_aws.NewInstanceCache(instanceClient, utils.GetDurationEnv("INSTANCE_CACHE_TTL", 15*time.Second)),
+		Events:                        events,
+		UserRepository:                user_repositories.NewUserRepository(dbInstance),
+		ServiceRepository:             service_repositories.NewServiceRepository(dbInstance),
+		PlanRepository:                plan_repositories.NewPlanRepository(dbInstance),
+		InstanceRepository:            instanceRepository,
+		InstanceCredentialsRepository: instance_repositories.NewInstanceCredentialsRepository(dbInstance),
+		InstanceMemberRepository:      instanceMemberRepository,
+		ReconcileReportRepository:     instance_repositories.NewReconcileReportRepository(dbInstance),
+		LedgerRepository:              ledger_repositories.NewLedgerRepository(dbInstance),
+		PurchaseRepository:            payment_repositories.NewPurchaseRepository(dbInstance),
+		PaymentProvider:               payment_providers.NewPaymentProvider(),
+		NotificationRepository:        notification_repositories.NewNotificationRepository(dbInstance),
+		ScheduleRepository:            schedule_repositories.NewScheduleRepository(dbInstance),
+		WalletRepository:              wallet_repositories.NewWalletRepository(dbInstance),
+		BackupRepository:              backup_repositories.NewBackupRepository(dbInstance),
 	}
 }
 
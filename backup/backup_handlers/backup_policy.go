@@ -0,0 +1,98 @@
+package backup_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for the backup policy. An interval of 0 disables automatic backups.
+type UpdateInstanceBackupPolicyRequestBody struct {
+	IntervalHours int `json:"intervalHours" binding:"min=0,max=720"`
+	Retention     int `json:"retention" binding:"min=0,max=30"`
+}
+
+// GetInstanceBackupPolicy returns the automatic backup policy of the user's instance
+func GetInstanceBackupPolicy(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	policy, err := appCtx.BackupRepository.GetBackupPolicy(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get backup policy", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// UpdateInstanceBackupPolicy sets how often the user's instance is backed up and how many automatic backups are kept
+func UpdateInstanceBackupPolicy(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request UpdateInstanceBackupPolicyRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Automatic backups must be pruned, storage is paid for
+	if request.IntervalHours > 0 && request.Retention == 0 {
+		utils.HandleError(c, http.StatusBadRequest, "Retention is required for automatic backups", errors.New("missing retention"), userEmail)
+		return
+	}
+
+	// Storage is billed to the owner
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	policy := backup_models.InstanceBackupPolicy{
+		InstanceID:    instance.ID,
+		IntervalHours: request.IntervalHours,
+		Retention:     request.Retention,
+	}
+	if err := appCtx.BackupRepository.SaveBackupPolicy(&policy); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update backup policy", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
@@ -0,0 +1,106 @@
+package backup_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// Automatic backups are limited by the retention of the backup policy instead
+const maxManualBackupsPerInstance = 10
+
+// The payload for taking a backup
+type CreateInstanceBackupRequestBody struct {
+	Note string `json:"note" binding:"max=200"`
+}
+
+// GetInstanceBackups returns the backups of the user's instance, newest first
+func GetInstanceBackups(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	backups, err := appCtx.BackupRepository.GetInstanceBackups(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get backups", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// CreateInstanceBackup starts a backup of the user's instance. The backup is pending until its snapshot completes.
+func CreateInstanceBackup(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request CreateInstanceBackupRequestBody
+	// The note is optional, so is the body
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	count, err := appCtx.BackupRepository.CountManualBackups(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get backups", err, userEmail)
+		return
+	}
+	if count >= maxManualBackupsPerInstance {
+		utils.HandleError(c, http.StatusBadRequest, "Too many backups", errors.New("backup limit reached"), userEmail)
+		return
+	}
+
+	backup, err := backup_lifecycle.TakeBackup(c, appCtx, instance, false, request.Note, userEmail)
+	if errors.Is(err, backup_lifecycle.ErrBackupUnavailable) {
+		utils.HandleError(c, http.StatusConflict, "Instance cannot be backed up in its current status", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create backup", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, backup)
+}
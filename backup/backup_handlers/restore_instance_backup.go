@@ -0,0 +1,84 @@
+package backup_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// RestoreInstanceBackup replaces the disk of the user's stopped instance with one of their backups.
+// The backup may come from another instance, including a terminated one, running the same service.
+// The restore runs in the background, the instance is restoring until it is done.
+func RestoreInstanceBackup(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	backupID64, err := strconv.ParseUint(c.Param("backupId"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid backup id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	backup, err := appCtx.BackupRepository.GetUserBackup(instance.UserID, uint(backupID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Backup not found", err, userEmail)
+		return
+	}
+
+	if backup.Status != backup_models.BackupStatusCompleted {
+		utils.HandleError(c, http.StatusConflict, "Backup is not completed", errors.New("backup is "+string(backup.Status)), userEmail)
+		return
+	}
+
+	if backup.ServiceID != instance.ServiceID {
+		utils.HandleError(c, http.StatusBadRequest, "Backup is of another service", errors.New("service mismatch"), userEmail)
+		return
+	}
+
+	plan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get plan", err, userEmail)
+		return
+	}
+
+	if int(backup.SizeGB) > plan.Disk {
+		utils.HandleError(c, http.StatusBadRequest, "Backup does not fit the disk of the instance's plan", errors.New("backup too large"), userEmail)
+		return
+	}
+
+	err = backup_lifecycle.RestoreBackup(appCtx, instance, backup, int32(plan.Disk), userEmail)
+	if errors.Is(err, instance_repositories.ErrIllegalTransition) {
+		utils.HandleError(c, http.StatusConflict, "Instance must be stopped to restore a backup", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to restore backup", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,75 @@
+package backup_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetBackups returns the backups of all instances of the user, including terminated ones
+func GetBackups(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	backups, err := appCtx.BackupRepository.GetUserBackups(user.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get backups", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, backups)
+}
+
+// DeleteBackup deletes a backup of the user after billing the storage it used
+func DeleteBackup(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	backupID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid backup id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	backup, err := appCtx.BackupRepository.GetUserBackup(user.ID, uint(backupID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Backup not found", err, userEmail)
+		return
+	}
+
+	// The snapshot cannot be deleted while it is being taken
+	if backup.Status == backup_models.BackupStatusPending {
+		utils.HandleError(c, http.StatusConflict, "Backup is still being taken", errors.New("backup is pending"), userEmail)
+		return
+	}
+
+	// An archived instance would lose its disk
+	instance, err := appCtx.InstanceRepository.GetInstance(backup.InstanceID)
+	if err == nil && instance.ArchiveBackupID != nil && *instance.ArchiveBackupID == backup.ID {
+		utils.HandleError(c, http.StatusConflict, "Backup holds an archived instance", errors.New("backup is an archive"), userEmail)
+		return
+	}
+
+	if err := backup_lifecycle.DeleteBackup(c, appCtx, backup); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to delete backup", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,144 @@
+package backup_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// RunBackups keeps backups up to date every interval. It never returns.
+// Each pass completes finished snapshots, takes the automatic backups that are due,
+// deletes those beyond retention and charges the storage of all completed backups.
+func RunBackups(appCtx *app.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx := context.Background()
+
+		if err := refreshPendingBackups(ctx, appCtx); err != nil {
+			log.Printf("backups: Error: failed to refresh pending backups: %v", err)
+		}
+
+		if err := runBackupPolicies(ctx, appCtx, time.Now()); err != nil {
+			log.Printf("backups: Error: failed to run backup policies: %v", err)
+		}
+
+		if err := chargeBackupStorage(appCtx, time.Now()); err != nil {
+			log.Printf("backups: Error: failed to charge backup storage: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+func refreshPendingBackups(ctx context.Context, appCtx *app.Context) error {
+	backups, err := appCtx.BackupRepository.GetBackupsByStatus(backup_models.BackupStatusPending)
+	if err != nil {
+		return err
+	}
+	if len(*backups) == 0 {
+		return nil
+	}
+
+	snapshotIds := make([]string, 0, len(*backups))
+	for _, backup := range *backups {
+		snapshotIds = append(snapshotIds, backup.SnapshotID)
+	}
+
+	snapshots, err := appCtx.InstanceClient.DescribeSnapshots(ctx, snapshotIds)
+	if err != nil {
+		return err
+	}
+
+	snapshotsById := make(map[string]*instance_aws.AWSSnapshot, len(*snapshots))
+	for i := range *snapshots {
+		snapshotsById[(*snapshots)[i].Id] = &(*snapshots)[i]
+	}
+
+	for i := range *backups {
+		backup := &(*backups)[i]
+		if err := backup_lifecycle.RefreshBackup(appCtx, backup, snapshotsById[backup.SnapshotID]); err != nil {
+			log.Printf("backups: Error: backup %d: %v", backup.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func runBackupPolicies(ctx context.Context, appCtx *app.Context, now time.Time) error {
+	policies, err := appCtx.BackupRepository.GetDueBackupPolicies(now)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range *policies {
+		instance, err := appCtx.InstanceRepository.GetInstance(policy.InstanceID)
+		if err != nil {
+			log.Printf("backups: Error: instance %d: %v", policy.InstanceID, err)
+			continue
+		}
+
+		// Instances about to be deleted only get their final backup
+		if instance.Status == instance_models.InstanceStatusPendingDeletion {
+			continue
+		}
+
+		// Instances that are busy are backed up on a later pass
+		reason := fmt.Sprintf("automatic backup every %d hours", policy.IntervalHours)
+		_, err = backup_lifecycle.TakeBackup(ctx, appCtx, instance, true, reason, "backup-policy")
+		if err != nil && !errors.Is(err, backup_lifecycle.ErrBackupUnavailable) {
+			log.Printf("backups: Error: instance %d: %v", instance.ID, err)
+		}
+
+		if err := pruneBackups(ctx, appCtx, policy.InstanceID, policy.Retention); err != nil {
+			log.Printf("backups: Error: instance %d: failed to prune backups: %v", instance.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// pruneBackups deletes the oldest automatic backups of the instance beyond its retention
+func pruneBackups(ctx context.Context, appCtx *app.Context, instanceID uint, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	backups, err := appCtx.BackupRepository.GetExpiredAutomaticBackups(instanceID, retention)
+	if err != nil {
+		return err
+	}
+
+	for i := range *backups {
+		if err := backup_lifecycle.DeleteBackup(ctx, appCtx, &(*backups)[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func chargeBackupStorage(appCtx *app.Context, now time.Time) error {
+	backups, err := appCtx.BackupRepository.GetBackupsByStatus(backup_models.BackupStatusCompleted)
+	if err != nil {
+		return err
+	}
+
+	for i := range *backups {
+		backup := &(*backups)[i]
+		if err := backup_lifecycle.ChargeStorage(appCtx, backup, now); err != nil {
+			log.Printf("backups: Error: backup %d: %v", backup.ID, err)
+		}
+	}
+
+	return nil
+}
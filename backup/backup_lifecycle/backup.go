@@ -0,0 +1,127 @@
+package backup_lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+)
+
+// How long a restore may take before the instance is marked failed
+const restoreTimeout = 30 * time.Minute
+
+var ErrBackupUnavailable = errors.New("instance cannot be backed up in its current status")
+
+// TakeBackup starts a snapshot of the instance's disk. The backup stays pending until the
+// backup job sees the snapshot completed.
+func TakeBackup(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, automatic bool, note string, actor string) (*backup_models.InstanceBackup, error) {
+	switch instance.Status {
+	case instance_models.InstanceStatusRunning, instance_models.InstanceStatusStopped, instance_models.InstanceStatusArchiving, instance_models.InstanceStatusPendingDeletion:
+	default:
+		return nil, ErrBackupUnavailable
+	}
+
+	tags := map[string]string{
+		instance_aws.TagInstanceID: strconv.FormatUint(uint64(instance.ID), 10),
+		instance_aws.TagUserID:     strconv.FormatUint(uint64(instance.UserID), 10),
+	}
+	snapshot, err := appCtx.InstanceClient.CreateSnapshot(ctx, &instance.RealID, fmt.Sprintf("Backup of instance %d", instance.ID), tags)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := backup_models.InstanceBackup{
+		InstanceID: instance.ID,
+		UserID:     instance.UserID,
+		ServiceID:  instance.ServiceID,
+		SnapshotID: snapshot.Id,
+		Status:     backup_models.BackupStatusPending,
+		Automatic:  automatic,
+		Note:       note,
+		CreatedBy:  actor,
+		SizeGB:     snapshot.SizeGB,
+	}
+	if err := appCtx.BackupRepository.CreateBackup(&backup); err != nil {
+		// Do not leave a snapshot nobody pays for
+		appCtx.InstanceClient.DeleteSnapshot(ctx, snapshot.Id)
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+// DeleteBackup bills the storage used so far, deletes the snapshot and removes the backup
+func DeleteBackup(ctx context.Context, appCtx *app.Context, backup *backup_models.InstanceBackup) error {
+	if backup.Status == backup_models.BackupStatusCompleted {
+		if err := ChargeStorage(appCtx, backup, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if backup.Status != backup_models.BackupStatusFailed {
+		if err := appCtx.InstanceClient.DeleteSnapshot(ctx, backup.SnapshotID); err != nil {
+			return err
+		}
+	}
+
+	return appCtx.BackupRepository.DeleteBackup(backup.ID)
+}
+
+// RestoreBackup moves the stopped instance to restoring and replaces its disk with the backup
+// in the background. The instance ends up stopped, or failed if the restore did not succeed.
+// Returns instance_repositories.ErrIllegalTransition when the instance is not stopped.
+func RestoreBackup(appCtx *app.Context, instance *instance_models.Instance, backup *backup_models.InstanceBackup, sizeGB int32, actor string) error {
+	reason := fmt.Sprintf("restoring backup %d", backup.ID)
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusRestoring, actor, reason); err != nil {
+		return err
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+		defer cancel()
+
+		label := notification_delivery.InstanceLabel(instance)
+		message := fmt.Sprintf("Instance %s was restored from backup %d", label, backup.ID)
+
+		err := appCtx.InstanceClient.RestoreRootVolume(ctx, &instance.RealID, backup.SnapshotID, sizeGB)
+		if err != nil {
+			log.Printf("%d: Failed to restore backup %d: %v", instance.ID, backup.ID, err)
+			appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusFailed, actor, fmt.Sprintf("restore of backup %d failed: %v", backup.ID, err))
+			message = fmt.Sprintf("Instance %s could not be restored from backup %d", label, backup.ID)
+		} else if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, actor, fmt.Sprintf("restored backup %d", backup.ID)); err != nil {
+			log.Printf("%d: Failed to finish restore of backup %d: %v", instance.ID, backup.ID, err)
+		}
+
+		if err := notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindBackupRestored, message); err != nil {
+			log.Printf("%d: Failed to notify restore: %v", instance.ID, err)
+		}
+	}()
+
+	return nil
+}
+
+// RefreshBackup completes or fails a pending backup once its snapshot has finished
+func RefreshBackup(appCtx *app.Context, backup *backup_models.InstanceBackup, snapshot *instance_aws.AWSSnapshot) error {
+	// The snapshot is gone, e.g. deleted by hand
+	if snapshot == nil {
+		return appCtx.BackupRepository.FailBackup(backup)
+	}
+
+	switch snapshot.State {
+	case instance_aws.SnapshotStateCompleted:
+		return appCtx.BackupRepository.CompleteBackup(backup, snapshot.SizeGB, time.Now())
+	case instance_aws.SnapshotStateError:
+		return appCtx.BackupRepository.FailBackup(backup)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package backup_lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCyclesPerGBMonth = 10
+	hoursPerMonth           = 730
+)
+
+// ChargeStorage bills the storage of a completed backup since it was last charged. Backups of
+// existing instances are paid from the instance's funding account, those of terminated
+// instances from the owner's account. Only whole cycles are billed, the remainder is carried over.
+func ChargeStorage(appCtx *app.Context, backup *backup_models.InstanceBackup, now time.Time) error {
+	rate := CyclesPerHour(backup)
+	if backup.ChargedUntil == nil || rate <= 0 {
+		return nil
+	}
+
+	cycles := uint(now.Sub(*backup.ChargedUntil).Hours() * rate)
+	if cycles == 0 {
+		return nil
+	}
+
+	account := ledger_repositories.UserAccount(backup.UserID)
+	instance, err := appCtx.InstanceRepository.GetInstance(backup.InstanceID)
+	if err == nil {
+		account = instance_billing.FundingAccount(instance)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	// Keyed on the charge start so a retry after a failed save cannot charge twice
+	transaction, err := appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+		From:           account,
+		To:             ledger_repositories.SystemAccount(ledger_models.SystemAccountBurned),
+		Amount:         cycles,
+		Reason:         ledger_models.ReasonBackupStorage,
+		Description:    fmt.Sprintf("Storage of backup %d (%d GB)", backup.ID, backup.SizeGB),
+		Actor:          "backups",
+		UserID:         backup.UserID,
+		InstanceID:     backup.InstanceID,
+		IdempotencyKey: fmt.Sprintf("backup:%d:%d", backup.ID, backup.ChargedUntil.UnixNano()),
+		ClampToBalance: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to charge backup storage: %v", err)
+	}
+
+	// An empty account pays nothing, ChargedUntil stays put and the storage is charged on a later pass
+	if transaction == nil {
+		return nil
+	}
+
+	// A partial payment covers only part of the period, the remainder is charged from the new ChargedUntil
+	charged := transaction.Credited()
+	chargedUntil := backup.ChargedUntil.Add(time.Duration(float64(charged) / rate * float64(time.Hour)))
+
+	return appCtx.BackupRepository.AddBackupCharge(backup, chargedUntil, charged)
+}
+
+// CyclesPerHour is the storage rate of a backup. Each GB costs BACKUP_CYCLES_PER_GB_MONTH cycles a month.
+func CyclesPerHour(backup *backup_models.InstanceBackup) float64 {
+	cyclesPerGBMonth, err := strconv.ParseFloat(os.Getenv("BACKUP_CYCLES_PER_GB_MONTH"), 64)
+	if err != nil || cyclesPerGBMonth < 0 {
+		cyclesPerGBMonth = defaultCyclesPerGBMonth
+	}
+	return float64(backup.SizeGB) * cyclesPerGBMonth / hoursPerMonth
+}
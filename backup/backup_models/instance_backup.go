@@ -0,0 +1,41 @@
+package backup_models
+
+import (
+	"time"
+)
+
+type BackupStatus string
+
+const (
+	BackupStatusPending   BackupStatus = "pending" // Snapshot being taken
+	BackupStatusCompleted BackupStatus = "completed"
+	BackupStatusFailed    BackupStatus = "failed"
+)
+
+// InstanceBackup is a snapshot of the disk of an instance. Backups outlive the instance
+// so a terminated world can be restored into another instance of the same service.
+type InstanceBackup struct {
+	ID            uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+	InstanceID    uint         `gorm:"not null;index" json:"instanceId"`
+	UserID        uint         `gorm:"not null;index" json:"userId"` // Owner of the instance, who pays for the storage
+	ServiceID     uint         `gorm:"not null" json:"serviceId"`
+	SnapshotID    string       `gorm:"not null;uniqueIndex" json:"-"`
+	Status        BackupStatus `gorm:"not null;index" json:"status"`
+	Automatic     bool         `gorm:"not null;default:false" json:"automatic"` // Taken by the backup policy and pruned by its retention
+	Note          string       `json:"note"`
+	CreatedBy     string       `json:"createdBy"`
+	SizeGB        int32        `json:"sizeGb"`
+	CompletedAt   *time.Time   `json:"completedAt"`
+	ChargedUntil  *time.Time   `json:"-"`                                       // Storage is billed up to this time
+	CyclesCharged int64        `gorm:"not null;default:0" json:"cyclesCharged"` // Storage cost billed so far
+}
+
+// InstanceBackupPolicy takes automatic backups of an instance
+type InstanceBackupPolicy struct {
+	InstanceID    uint      `gorm:"primaryKey;autoIncrement:false" json:"instanceId"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+	IntervalHours int       `gorm:"not null;default:0" json:"intervalHours"` // Time between automatic backups, 0 to take none
+	Retention     int       `gorm:"not null;default:0" json:"retention"`     // Number of automatic backups kept, the oldest are deleted first
+}
@@ -0,0 +1,149 @@
+package backup_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BackupRepository struct {
+	DB *gorm.DB
+}
+
+func NewBackupRepository(db *gorm.DB) *BackupRepository {
+	return &BackupRepository{DB: db}
+}
+
+func (r *BackupRepository) CreateBackup(backup *backup_models.InstanceBackup) error {
+	return r.DB.Create(backup).Error
+}
+
+// GetInstanceBackups returns the backups of the instance, newest first
+func (r *BackupRepository) GetInstanceBackups(instanceID uint) (*[]backup_models.InstanceBackup, error) {
+	var backups []backup_models.InstanceBackup
+	err := r.DB.Where("instance_id = ?", instanceID).Order("created_at desc").Find(&backups).Error
+	return &backups, err
+}
+
+// GetUserBackups returns the backups of all instances of the user, including terminated ones, newest first
+func (r *BackupRepository) GetUserBackups(userID uint) (*[]backup_models.InstanceBackup, error) {
+	var backups []backup_models.InstanceBackup
+	err := r.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&backups).Error
+	return &backups, err
+}
+
+func (r *BackupRepository) GetBackup(backupID uint) (*backup_models.InstanceBackup, error) {
+	var backup backup_models.InstanceBackup
+	err := r.DB.First(&backup, backupID).Error
+	return &backup, err
+}
+
+func (r *BackupRepository) GetUserBackup(userID uint, backupID uint) (*backup_models.InstanceBackup, error) {
+	var backup backup_models.InstanceBackup
+	err := r.DB.Where("user_id = ?", userID).First(&backup, backupID).Error
+	return &backup, err
+}
+
+func (r *BackupRepository) CountManualBackups(instanceID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&backup_models.InstanceBackup{}).
+		Where("instance_id = ? AND automatic = ? AND status <> ?", instanceID, false, backup_models.BackupStatusFailed).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *BackupRepository) GetBackupsByStatus(status backup_models.BackupStatus) (*[]backup_models.InstanceBackup, error) {
+	var backups []backup_models.InstanceBackup
+	err := r.DB.Where("status = ?", status).Order("id").Find(&backups).Error
+	return &backups, err
+}
+
+// CompleteBackup marks the backup completed, its storage is billed from now on
+func (r *BackupRepository) CompleteBackup(backup *backup_models.InstanceBackup, sizeGB int32, now time.Time) error {
+	err := r.DB.Model(backup).Updates(map[string]interface{}{
+		"status":        backup_models.BackupStatusCompleted,
+		"size_gb":       sizeGB,
+		"completed_at":  now,
+		"charged_until": now,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	backup.Status = backup_models.BackupStatusCompleted
+	backup.SizeGB = sizeGB
+	backup.CompletedAt = &now
+	backup.ChargedUntil = &now
+	return nil
+}
+
+func (r *BackupRepository) FailBackup(backup *backup_models.InstanceBackup) error {
+	if err := r.DB.Model(backup).Update("status", backup_models.BackupStatusFailed).Error; err != nil {
+		return err
+	}
+
+	backup.Status = backup_models.BackupStatusFailed
+	return nil
+}
+
+// AddBackupCharge records storage billed up to until
+func (r *BackupRepository) AddBackupCharge(backup *backup_models.InstanceBackup, until time.Time, cycles int64) error {
+	err := r.DB.Model(backup).Updates(map[string]interface{}{
+		"charged_until":  until,
+		"cycles_charged": gorm.Expr("cycles_charged + ?", cycles),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	backup.ChargedUntil = &until
+	backup.CyclesCharged += cycles
+	return nil
+}
+
+func (r *BackupRepository) DeleteBackup(backupID uint) error {
+	return r.DB.Delete(&backup_models.InstanceBackup{}, backupID).Error
+}
+
+// GetBackupPolicy returns the policy of the instance, a disabled one if it has none
+func (r *BackupRepository) GetBackupPolicy(instanceID uint) (*backup_models.InstanceBackupPolicy, error) {
+	policy := backup_models.InstanceBackupPolicy{InstanceID: instanceID}
+	err := r.DB.Where(backup_models.InstanceBackupPolicy{InstanceID: instanceID}).FirstOrInit(&policy).Error
+	return &policy, err
+}
+
+func (r *BackupRepository) DeleteBackupPolicy(instanceID uint) error {
+	return r.DB.Delete(&backup_models.InstanceBackupPolicy{}, instanceID).Error
+}
+
+func (r *BackupRepository) SaveBackupPolicy(policy *backup_models.InstanceBackupPolicy) error {
+	return r.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(policy).Error
+}
+
+// GetDueBackupPolicies returns the policies of existing instances whose latest automatic
+// backup is older than their interval
+func (r *BackupRepository) GetDueBackupPolicies(now time.Time) (*[]backup_models.InstanceBackupPolicy, error) {
+	var policies []backup_models.InstanceBackupPolicy
+	err := r.DB.
+		Joins("JOIN instances ON instances.id = instance_backup_policies.instance_id AND instances.deleted_at IS NULL").
+		Where("instance_backup_policies.interval_hours > 0").
+		Where(`NOT EXISTS (SELECT 1 FROM instance_backups WHERE instance_backups.instance_id = instance_backup_policies.instance_id
+			AND instance_backups.automatic AND instance_backups.status <> ?
+			AND instance_backups.created_at > ?::timestamptz - instance_backup_policies.interval_hours * interval '1 hour')`,
+			backup_models.BackupStatusFailed, now).
+		Find(&policies).Error
+	return &policies, err
+}
+
+// GetExpiredAutomaticBackups returns the completed automatic backups of the instance beyond the newest retention ones
+func (r *BackupRepository) GetExpiredAutomaticBackups(instanceID uint, retention int) (*[]backup_models.InstanceBackup, error) {
+	var backups []backup_models.InstanceBackup
+	err := r.DB.
+		Where("instance_id = ? AND automatic AND status = ?", instanceID, backup_models.BackupStatusCompleted).
+		Order("created_at desc").
+		Offset(retention).
+		Find(&backups).Error
+	return &backups, err
+}
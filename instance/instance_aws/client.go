@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -16,13 +18,21 @@ import (
 )
 
 type AWSInstance struct {
-	Id         string    `json:"-"`
-	Type       string    `json:"type"`
-	LaunchTime time.Time `json:"launchTime"`
-	PublicIp   string    `json:"publicIp"`
-	State      string    `json:"state"`
+	Id         string            `json:"-"`
+	Type       string            `json:"type"`
+	LaunchTime time.Time         `json:"launchTime"`
+	PublicIp   string            `json:"publicIp"`
+	State      string            `json:"state"`
+	Tags       map[string]string `json:"-"`
 }
 
+// Tags set on every instance created by this API, used to find instances without a record
+const (
+	TagManaged    = "gshub:managed"
+	TagInstanceID = "gshub:instance-id"
+	TagUserID     = "gshub:user-id"
+)
+
 type AWSClient struct {
 	ec2 *ec2.Client
 	ssm *ssm.Client
@@ -30,11 +40,20 @@ type AWSClient struct {
 
 type AWSInstanceType string
 
-const (
-	InstanceTypeSmall  AWSInstanceType = "t3.small"
-	InstanceTypeMedium AWSInstanceType = "t3.medium"
-	InstanceTypeLarge  AWSInstanceType = "t3.large"
-)
+// CreateInstanceOptions holds the per-instance settings used when launching an instance
+type CreateInstanceOptions struct {
+	// AgentEnv is written to /etc/gshub/agent.env and passed to the instance agent container
+	AgentEnv map[string]string
+
+	// Tags are added to the instance in addition to TagManaged
+	Tags map[string]string
+
+	// DiskSize is the size of the root volume in GB, the image default when 0
+	DiskSize int32
+
+	// SnapshotID launches the instance with a root volume restored from the snapshot instead of the base image
+	SnapshotID string
+}
 
 // NewClient initializes a new instance of Client
 func NewAWSClient() *AWSClient {
@@ -54,10 +73,29 @@ func NewAWSClient() *AWSClient {
 	}
 }
 
-func (c *AWSClient) CreateInstance(ctx context.Context, instanceType *AWSInstanceType) (*AWSInstance, error) {
-	imageId := os.Getenv("AWS_IMAGE_ID_BASE")
+func (c *AWSClient) CreateInstance(ctx context.Context, instanceType *AWSInstanceType, options *CreateInstanceOptions) (*AWSInstance, error) {
 	keyName := os.Getenv("AWS_KEY_PAIR_NAME")
 
+	// The image has to match the architecture of the instance type
+	info, err := c.DescribeInstanceType(ctx, string(*instanceType))
+	if err != nil {
+		return &AWSInstance{}, err
+	}
+
+	imageId, err := imageForArchitecture(info.Architecture)
+	if err != nil {
+		return &AWSInstance{}, err
+	}
+
+	// The root volume of an image cannot be swapped at launch, so the snapshot gets a temporary image
+	if options.SnapshotID != "" {
+		imageId, err = c.registerSnapshotImage(ctx, imageId, options.SnapshotID, info.Architecture)
+		if err != nil {
+			return &AWSInstance{}, err
+		}
+		defer c.ec2.DeregisterImage(ctx, &ec2.DeregisterImageInput{ImageId: aws.String(imageId)})
+	}
+
 	// Read the server-setup script file
 	data, err := os.ReadFile("./scripts/instance-setup.sh")
 	if err != nil {
@@ -65,7 +103,12 @@ func (c *AWSClient) CreateInstance(ctx context.Context, instanceType *AWSInstanc
 	}
 
 	// Convert the file contents to a string
-	encoded := base64.StdEncoding.EncodeToString(data)
+	encoded := base64.StdEncoding.EncodeToString([]byte(buildUserData(string(data), options.AgentEnv)))
+
+	tags := []types.Tag{{Key: aws.String(TagManaged), Value: aws.String("true")}}
+	for key, value := range options.Tags {
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
 
 	runInstancesInput := &ec2.RunInstancesInput{
 		ImageId:      &imageId,
@@ -74,6 +117,26 @@ func (c *AWSClient) CreateInstance(ctx context.Context, instanceType *AWSInstanc
 		MaxCount:     aws.Int32(1),
 		KeyName:      &keyName,
 		UserData:     aws.String(encoded),
+		TagSpecifications: []types.TagSpecification{
+			{ResourceType: types.ResourceTypeInstance, Tags: tags},
+		},
+	}
+
+	if options.DiskSize > 0 {
+		images, err := c.ec2.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{imageId}})
+		if err != nil || len(images.Images) == 0 {
+			return &AWSInstance{}, fmt.Errorf("failed to describe image: %v", err)
+		}
+
+		runInstancesInput.BlockDeviceMappings = []types.BlockDeviceMapping{
+			{
+				DeviceName: images.Images[0].RootDeviceName,
+				Ebs: &types.EbsBlockDevice{
+					VolumeSize:          aws.Int32(options.DiskSize),
+					DeleteOnTermination: aws.Bool(true),
+				},
+			},
+		}
 	}
 
 	result, err := c.ec2.RunInstances(ctx, runInstancesInput)
@@ -91,41 +154,78 @@ func (c *AWSClient) CreateInstance(ctx context.Context, instanceType *AWSInstanc
 	}, nil
 }
 
+// GetInstances describes the given instances. Instances that do not exist are left out.
 func (c *AWSClient) GetInstances(ctx context.Context, instanceIds *[]string) (*[]AWSInstance, error) {
 	if len(*instanceIds) == 0 {
 		return &[]AWSInstance{}, nil
 	}
 
-	result, err := c.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-		InstanceIds: *instanceIds,
+	// Filtering by id instead of passing InstanceIds avoids failing the whole call on unknown ids
+	return c.describeInstances(ctx, []types.Filter{
+		{
+			Name:   aws.String("instance-id"),
+			Values: *instanceIds,
+		},
 	})
-	if err != nil {
-		return &[]AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
-	}
-
-	var instances []AWSInstance
+}
 
-	for _, r := range result.Reservations {
-		i := r.Instances[0]
+// GetManagedInstances describes all instances tagged as created by this API that are not being terminated
+func (c *AWSClient) GetManagedInstances(ctx context.Context) (*[]AWSInstance, error) {
+	return c.describeInstances(ctx, []types.Filter{
+		{
+			Name:   aws.String("tag-key"),
+			Values: []string{TagManaged},
+		},
+		{
+			Name:   aws.String("instance-state-name"),
+			Values: []string{"pending", "running", "stopping", "stopped"},
+		},
+	})
+}
 
-		publicIp := ""
+func (c *AWSClient) describeInstances(ctx context.Context, filters []types.Filter) (*[]AWSInstance, error) {
+	instances := []AWSInstance{}
 
-		if i.State.Name == types.InstanceStateNameRunning {
-			publicIp = *i.PublicIpAddress
+	paginator := ec2.NewDescribeInstancesPaginator(c.ec2, &ec2.DescribeInstancesInput{
+		Filters: filters,
+	})
+	for paginator.HasMorePages() {
+		result, err := paginator.NextPage(ctx)
+		if err != nil {
+			return &[]AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
 		}
 
-		instances = append(instances, AWSInstance{
-			Id:         *i.InstanceId,
-			Type:       string(i.InstanceType),
-			LaunchTime: *i.LaunchTime,
-			State:      string(i.State.Name),
-			PublicIp:   publicIp,
-		})
+		for _, r := range result.Reservations {
+			for _, i := range r.Instances {
+				instances = append(instances, toAWSInstance(i))
+			}
+		}
 	}
 
 	return &instances, nil
 }
 
+func toAWSInstance(i types.Instance) AWSInstance {
+	publicIp := ""
+	if i.State.Name == types.InstanceStateNameRunning && i.PublicIpAddress != nil {
+		publicIp = *i.PublicIpAddress
+	}
+
+	tags := make(map[string]string, len(i.Tags))
+	for _, tag := range i.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return AWSInstance{
+		Id:         *i.InstanceId,
+		Type:       string(i.InstanceType),
+		LaunchTime: aws.ToTime(i.LaunchTime),
+		State:      string(i.State.Name),
+		PublicIp:   publicIp,
+		Tags:       tags,
+	}
+}
+
 func (c *AWSClient) GetInstance(ctx context.Context, instanceId *string) (*AWSInstance, error) {
 	result, err := c.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: []string{*instanceId},
@@ -134,20 +234,9 @@ func (c *AWSClient) GetInstance(ctx context.Context, instanceId *string) (*AWSIn
 		return &AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
 	}
 
-	instance := result.Reservations[0].Instances[0]
-
-	publicIp := ""
-	if instance.State.Name == types.InstanceStateNameRunning {
-		publicIp = *instance.PublicIpAddress
-	}
+	instance := toAWSInstance(result.Reservations[0].Instances[0])
 
-	return &AWSInstance{
-		Id:         *instance.InstanceId,
-		Type:       string(instance.InstanceType),
-		LaunchTime: *instance.LaunchTime,
-		State:      string(instance.State.Name),
-		PublicIp:   publicIp,
-	}, nil
+	return &instance, nil
 }
 
 func (c *AWSClient) UpdateInstance(ctx context.Context, instanceId *string, newInstanceType *AWSInstanceType) (*AWSInstance, error) {
@@ -181,6 +270,37 @@ func (c *AWSClient) UpdateInstance(ctx context.Context, instanceId *string, newI
 	return instance, nil
 }
 
+// ResizeRootVolume grows the root volume of the instance to sizeGB. Volumes cannot shrink.
+func (c *AWSClient) ResizeRootVolume(ctx context.Context, instanceId *string, sizeGB int32) error {
+	_, volumeId, err := c.rootVolume(ctx, instanceId)
+	if err != nil {
+		return err
+	}
+
+	volumes, err := c.ec2.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{*volumeId}})
+	if err != nil || len(volumes.Volumes) == 0 {
+		return fmt.Errorf("failed to describe volume: %v", err)
+	}
+
+	currentSize := aws.ToInt32(volumes.Volumes[0].Size)
+	if currentSize == sizeGB {
+		return nil
+	}
+	if currentSize > sizeGB {
+		return fmt.Errorf("cannot shrink volume from %d to %d GB", currentSize, sizeGB)
+	}
+
+	_, err = c.ec2.ModifyVolume(ctx, &ec2.ModifyVolumeInput{
+		VolumeId: volumeId,
+		Size:     aws.Int32(sizeGB),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to modify volume: %v", err)
+	}
+
+	return nil
+}
+
 func (c *AWSClient) StartInstances(ctx context.Context, instanceIds []string) error {
 	_, err := c.ec2.StartInstances(ctx, &ec2.StartInstancesInput{
 		InstanceIds: instanceIds,
@@ -239,6 +359,57 @@ func (c *AWSClient) GetRunningInstances(ctx context.Context) (*[]string, error)
 	return &instanceIds, nil
 }
 
+// DescribeInstanceType returns the capabilities of the instance type and whether it is
+// offered in the configured region. ErrUnknownInstanceType is returned for types that do not exist.
+func (c *AWSClient) DescribeInstanceType(ctx context.Context, instanceType string) (*InstanceTypeInfo, error) {
+	described, err := c.ec2.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		var apiErr interface{ ErrorCode() string }
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceType" {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownInstanceType, instanceType)
+		}
+		return nil, fmt.Errorf("failed to describe instance type: %v", err)
+	}
+	if len(described.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownInstanceType, instanceType)
+	}
+
+	typeInfo := described.InstanceTypes[0]
+	info := &InstanceTypeInfo{Name: instanceType}
+
+	if typeInfo.ProcessorInfo != nil {
+		for _, architecture := range typeInfo.ProcessorInfo.SupportedArchitectures {
+			// Prefer the 64 bit architectures our images are built for
+			if architecture == types.ArchitectureTypeX8664 || architecture == types.ArchitectureTypeArm64 {
+				info.Architecture = string(architecture)
+				break
+			}
+		}
+	}
+	if typeInfo.VCpuInfo != nil {
+		info.VCPUs = aws.ToInt32(typeInfo.VCpuInfo.DefaultVCpus)
+	}
+	if typeInfo.MemoryInfo != nil {
+		info.MemoryMiB = aws.ToInt64(typeInfo.MemoryInfo.SizeInMiB)
+	}
+
+	// Instance types are not offered in every region
+	offerings, err := c.ec2.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeRegion,
+		Filters: []types.Filter{
+			{Name: aws.String("instance-type"), Values: []string{instanceType}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance type offerings: %v", err)
+	}
+	info.Available = len(offerings.InstanceTypeOfferings) > 0
+
+	return info, nil
+}
+
 func (c *AWSClient) SendCommand(ctx context.Context, command *string, instanceIds *[]string) error {
 	commandInput := &ssm.SendCommandInput{
 		InstanceIds:  *instanceIds,
@@ -256,15 +427,28 @@ func (c *AWSClient) SendCommand(ctx context.Context, command *string, instanceId
 	return nil
 }
 
-func ParseInstanceType(instanceType string) (AWSInstanceType, error) {
-	switch instanceType {
-	case string(InstanceTypeSmall):
-		return InstanceTypeSmall, nil
-	case string(InstanceTypeMedium):
-		return InstanceTypeMedium, nil
-	case string(InstanceTypeLarge):
-		return InstanceTypeLarge, nil
-	default:
-		return "", errors.New("invalid instance type")
+// buildUserData prepends the commands writing the agent env file to the setup script
+func buildUserData(script string, agentEnv map[string]string) string {
+	keys := make([]string, 0, len(agentEnv))
+	for key := range agentEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var envFile strings.Builder
+	envFile.WriteString("mkdir -p /etc/gshub\n")
+	envFile.WriteString("install -m 600 /dev/null /etc/gshub/agent.env\n")
+	envFile.WriteString("cat > /etc/gshub/agent.env <<'EOF'\n")
+	for _, key := range keys {
+		envFile.WriteString(fmt.Sprintf("%s=%s\n", key, agentEnv[key]))
+	}
+	envFile.WriteString("EOF\n\n")
+
+	// Keep the shebang as the first line
+	shebang, rest, found := strings.Cut(script, "\n")
+	if !found || !strings.HasPrefix(shebang, "#!") {
+		return "#!/bin/bash\n\n" + envFile.String() + script
 	}
+
+	return shebang + "\n\n" + envFile.String() + rest
 }
@@ -0,0 +1,64 @@
+package instance_aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// CallbackAgent is a FakeAgent that makes the callbacks of the real agent, so records of the
+// fake provider go through startup and shutdown like those of EC2 instances. It uses the
+// callback URL, instance ID and secret from the agent env the instance was launched with.
+type CallbackAgent struct {
+	client *http.Client
+}
+
+// NewCallbackAgent initializes an agent calling the instance callback server
+func NewCallbackAgent() *CallbackAgent {
+	return &CallbackAgent{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (a *CallbackAgent) Startup(instance AWSInstance, agentEnv map[string]string) {
+	a.call(http.MethodGet, "startup", agentEnv, map[string]interface{}{"publicIp": instance.PublicIp})
+}
+
+func (a *CallbackAgent) Shutdown(instance AWSInstance, agentEnv map[string]string) {
+	a.call(http.MethodPost, "shutdown", agentEnv, map[string]interface{}{"burnedCycleAmount": 0})
+}
+
+// call sends the callback, failures are only logged like a real agent would
+func (a *CallbackAgent) call(method string, path string, agentEnv map[string]string, payload interface{}) {
+	instanceID := agentEnv["GSHUB_INSTANCE_ID"]
+	if agentEnv["GSHUB_CALLBACK_URL"] == "" || instanceID == "" {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("fake agent: Error: instance %s: %v", instanceID, err)
+		return
+	}
+
+	url := fmt.Sprintf("%s/%s/%s", agentEnv["GSHUB_CALLBACK_URL"], path, instanceID)
+	request, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("fake agent: Error: instance %s: %v", instanceID, err)
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+agentEnv["GSHUB_INSTANCE_SECRET"])
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		log.Printf("fake agent: Error: instance %s: %s failed: %v", instanceID, path, err)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		log.Printf("fake agent: Error: instance %s: %s returned %s", instanceID, path, response.Status)
+	}
+}
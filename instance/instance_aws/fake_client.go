@@ -0,0 +1,580 @@
+package instance_aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// FakeClientConfig controls how long the fake provider keeps an instance in
+// each intermediate state. A zero delay makes the transition immediate.
+type FakeClientConfig struct {
+	PendingDelay      time.Duration // pending -> running
+	StoppingDelay     time.Duration // stopping -> stopped
+	ShuttingDownDelay time.Duration // shutting-down -> terminated
+
+	// AfterFunc runs f once delay has passed, time.AfterFunc when nil.
+	// Tests replace it to finish delayed transitions on demand.
+	AfterFunc func(delay time.Duration, f func())
+
+	// Agent is told when an instance boots or shuts down, nil for none
+	Agent FakeAgent
+}
+
+// FakeAgent stands in for the agent running on the instances of the fake provider.
+// It is called outside of the client lock and may call the client.
+type FakeAgent interface {
+	Startup(instance AWSInstance, agentEnv map[string]string)  // The instance is running
+	Shutdown(instance AWSInstance, agentEnv map[string]string) // A running instance is stopping or terminating
+}
+
+// FakeCommand is a command recorded by FakeClient.SendCommand
+type FakeCommand struct {
+	Command     string
+	InstanceIds []string
+}
+
+// FakeClient is an in-memory InstanceClient that simulates EC2 state transitions.
+// It is safe for concurrent use.
+type FakeClient struct {
+	mu          sync.Mutex
+	config      FakeClientConfig
+	instances   map[string]*AWSInstance
+	failures    map[string]error
+	commands    []FakeCommand
+	agentEnvs   map[string]map[string]string
+	diskSizes   map[string]int32
+	snapshots   map[string]*AWSSnapshot
+	generations map[string]int
+	nextID      int
+	nextIP      int
+}
+
+// NewFakeClient initializes a new in-memory provider
+func NewFakeClient(config FakeClientConfig) *FakeClient {
+	if config.AfterFunc == nil {
+		config.AfterFunc = func(delay time.Duration, f func()) { time.AfterFunc(delay, f) }
+	}
+
+	return &FakeClient{
+		config:      config,
+		instances:   make(map[string]*AWSInstance),
+		failures:    make(map[string]error),
+		agentEnvs:   make(map[string]map[string]string),
+		diskSizes:   make(map[string]int32),
+		snapshots:   make(map[string]*AWSSnapshot),
+		generations: make(map[string]int),
+	}
+}
+
+// InjectFailure makes every call to the named method (e.g. "StartInstances") return err
+// until ClearFailure is called.
+func (c *FakeClient) InjectFailure(method string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures[method] = err
+}
+
+// ClearFailure removes a failure injected with InjectFailure
+func (c *FakeClient) ClearFailure(method string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.failures, method)
+}
+
+// AgentEnv returns the agent env the instance was launched with
+func (c *FakeClient) AgentEnv(instanceId string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.agentEnvs[instanceId]
+}
+
+// Commands returns the commands sent so far
+func (c *FakeClient) Commands() []FakeCommand {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]FakeCommand(nil), c.commands...)
+}
+
+func (c *FakeClient) CreateInstance(ctx context.Context, instanceType *AWSInstanceType, options *CreateInstanceOptions) (*AWSInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("CreateInstance"); err != nil {
+		return &AWSInstance{}, fmt.Errorf("failed to create instance: %v", err)
+	}
+
+	diskSize := options.DiskSize
+	if options.SnapshotID != "" {
+		snapshot, exists := c.snapshots[options.SnapshotID]
+		if !exists || snapshot.State != SnapshotStateCompleted {
+			return &AWSInstance{}, fmt.Errorf("failed to create instance: snapshot %s not found", options.SnapshotID)
+		}
+		diskSize = max(diskSize, snapshot.SizeGB)
+	}
+
+	c.nextID++
+	tags := map[string]string{TagManaged: "true"}
+	for key, value := range options.Tags {
+		tags[key] = value
+	}
+
+	instance := &AWSInstance{
+		Id:         fmt.Sprintf("i-fake%013d", c.nextID),
+		Type:       string(*instanceType),
+		LaunchTime: time.Now(),
+		State:      string(types.InstanceStateNamePending),
+		Tags:       tags,
+	}
+	c.instances[instance.Id] = instance
+	c.agentEnvs[instance.Id] = options.AgentEnv
+	c.diskSizes[instance.Id] = diskSize
+	c.transition(instance.Id, types.InstanceStateNamePending, types.InstanceStateNameRunning, c.config.PendingDelay)
+
+	copied := *instance
+	return &copied, nil
+}
+
+func (c *FakeClient) GetInstance(ctx context.Context, instanceId *string) (*AWSInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("GetInstance"); err != nil {
+		return &AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	instance, exists := c.instances[*instanceId]
+	if !exists {
+		return &AWSInstance{}, fmt.Errorf("failed to describe instance: %s not found", *instanceId)
+	}
+
+	copied := *instance
+	return &copied, nil
+}
+
+func (c *FakeClient) GetInstances(ctx context.Context, instanceIds *[]string) (*[]AWSInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(*instanceIds) == 0 {
+		return &[]AWSInstance{}, nil
+	}
+
+	if err := c.failure("GetInstances"); err != nil {
+		return &[]AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	instances := []AWSInstance{}
+	for _, id := range *instanceIds {
+		if instance, exists := c.instances[id]; exists {
+			instances = append(instances, *instance)
+		}
+	}
+
+	return &instances, nil
+}
+
+func (c *FakeClient) GetManagedInstances(ctx context.Context) (*[]AWSInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("GetManagedInstances"); err != nil {
+		return &[]AWSInstance{}, fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	instances := []AWSInstance{}
+	for _, instance := range c.instances {
+		terminating := instance.State == string(types.InstanceStateNameShuttingDown) || instance.State == string(types.InstanceStateNameTerminated)
+		if !terminating && instance.Tags[TagManaged] == "true" {
+			instances = append(instances, *instance)
+		}
+	}
+
+	return &instances, nil
+}
+
+func (c *FakeClient) GetRunningInstances(ctx context.Context) (*[]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("GetRunningInstances"); err != nil {
+		return nil, fmt.Errorf("failed to describe instances: %v", err)
+	}
+
+	var instanceIds []string
+	for id, instance := range c.instances {
+		if instance.State == string(types.InstanceStateNameRunning) {
+			instanceIds = append(instanceIds, id)
+		}
+	}
+
+	return &instanceIds, nil
+}
+
+func (c *FakeClient) UpdateInstance(ctx context.Context, instanceId *string, newInstanceType *AWSInstanceType) (*AWSInstance, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("UpdateInstance"); err != nil {
+		return &AWSInstance{}, fmt.Errorf("failed to modify instance type: %v", err)
+	}
+
+	instance, exists := c.instances[*instanceId]
+	if !exists {
+		return &AWSInstance{}, fmt.Errorf("failed to describe instance: %s not found", *instanceId)
+	}
+
+	if instance.Type == string(*newInstanceType) {
+		return &AWSInstance{}, errors.New("no changes")
+	}
+
+	if instance.State != string(types.InstanceStateNameStopped) {
+		return &AWSInstance{}, errors.New("cannot update a running instance")
+	}
+
+	instance.Type = string(*newInstanceType)
+
+	copied := *instance
+	return &copied, nil
+}
+
+// DiskSize returns the root volume size of the instance in GB
+func (c *FakeClient) DiskSize(instanceId string) int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diskSizes[instanceId]
+}
+
+func (c *FakeClient) ResizeRootVolume(ctx context.Context, instanceId *string, sizeGB int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("ResizeRootVolume"); err != nil {
+		return fmt.Errorf("failed to modify volume: %v", err)
+	}
+
+	if err := c.requireState([]string{*instanceId}); err != nil {
+		return fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	if c.diskSizes[*instanceId] > sizeGB {
+		return fmt.Errorf("cannot shrink volume from %d to %d GB", c.diskSizes[*instanceId], sizeGB)
+	}
+
+	c.diskSizes[*instanceId] = sizeGB
+	return nil
+}
+
+func (c *FakeClient) StartInstances(ctx context.Context, instanceIds []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("StartInstances"); err != nil {
+		return fmt.Errorf("unable to start instances, %v", err)
+	}
+
+	if err := c.requireState(instanceIds, types.InstanceStateNameStopped, types.InstanceStateNamePending, types.InstanceStateNameRunning); err != nil {
+		return fmt.Errorf("unable to start instances, %v", err)
+	}
+
+	for _, id := range instanceIds {
+		if c.instances[id].State != string(types.InstanceStateNameStopped) {
+			continue
+		}
+		c.instances[id].LaunchTime = time.Now()
+		c.transition(id, types.InstanceStateNamePending, types.InstanceStateNameRunning, c.config.PendingDelay)
+	}
+
+	return nil
+}
+
+func (c *FakeClient) StopInstances(ctx context.Context, instanceIds []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("StopInstances"); err != nil {
+		return fmt.Errorf("unable to stop instances, %v", err)
+	}
+
+	if err := c.requireState(instanceIds, types.InstanceStateNamePending, types.InstanceStateNameRunning, types.InstanceStateNameStopping, types.InstanceStateNameStopped); err != nil {
+		return fmt.Errorf("unable to stop instances, %v", err)
+	}
+
+	for _, id := range instanceIds {
+		state := c.instances[id].State
+		if state != string(types.InstanceStateNamePending) && state != string(types.InstanceStateNameRunning) {
+			continue
+		}
+		if state == string(types.InstanceStateNameRunning) {
+			c.notifyAgent(id, false)
+		}
+		c.instances[id].PublicIp = ""
+		c.transition(id, types.InstanceStateNameStopping, types.InstanceStateNameStopped, c.config.StoppingDelay)
+	}
+
+	return nil
+}
+
+func (c *FakeClient) TerminateInstances(ctx context.Context, instanceIds []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("TerminateInstances"); err != nil {
+		return fmt.Errorf("unable to terminate instances, %v", err)
+	}
+
+	if err := c.requireState(instanceIds); err != nil {
+		return fmt.Errorf("unable to terminate instances, %v", err)
+	}
+
+	for _, id := range instanceIds {
+		state := c.instances[id].State
+		if state == string(types.InstanceStateNameShuttingDown) || state == string(types.InstanceStateNameTerminated) {
+			continue
+		}
+		if state == string(types.InstanceStateNameRunning) {
+			c.notifyAgent(id, false)
+		}
+		c.instances[id].PublicIp = ""
+		c.transition(id, types.InstanceStateNameShuttingDown, types.InstanceStateNameTerminated, c.config.ShuttingDownDelay)
+	}
+
+	return nil
+}
+
+func (c *FakeClient) SendCommand(ctx context.Context, command *string, instanceIds *[]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("SendCommand"); err != nil {
+		return fmt.Errorf("failed to send command: %v", err)
+	}
+
+	if err := c.requireState(*instanceIds, types.InstanceStateNameRunning); err != nil {
+		return fmt.Errorf("failed to send command: %v", err)
+	}
+
+	c.commands = append(c.commands, FakeCommand{
+		Command:     *command,
+		InstanceIds: append([]string(nil), *instanceIds...),
+	})
+
+	return nil
+}
+
+// fakeInstanceTypes is the capability list of the fake provider
+var fakeInstanceTypes = map[string]InstanceTypeInfo{
+	"t3.small":   {Architecture: ArchitectureX86_64, VCPUs: 2, MemoryMiB: 2048},
+	"t3.medium":  {Architecture: ArchitectureX86_64, VCPUs: 2, MemoryMiB: 4096},
+	"t3.large":   {Architecture: ArchitectureX86_64, VCPUs: 2, MemoryMiB: 8192},
+	"t3.xlarge":  {Architecture: ArchitectureX86_64, VCPUs: 4, MemoryMiB: 16384},
+	"t3a.medium": {Architecture: ArchitectureX86_64, VCPUs: 2, MemoryMiB: 4096},
+	"t3a.large":  {Architecture: ArchitectureX86_64, VCPUs: 2, MemoryMiB: 8192},
+	"t4g.small":  {Architecture: ArchitectureArm64, VCPUs: 2, MemoryMiB: 2048},
+	"t4g.medium": {Architecture: ArchitectureArm64, VCPUs: 2, MemoryMiB: 4096},
+	"t4g.large":  {Architecture: ArchitectureArm64, VCPUs: 2, MemoryMiB: 8192},
+	"t4g.xlarge": {Architecture: ArchitectureArm64, VCPUs: 4, MemoryMiB: 16384},
+	"c7g.large":  {Architecture: ArchitectureArm64, VCPUs: 2, MemoryMiB: 4096},
+	"c7g.xlarge": {Architecture: ArchitectureArm64, VCPUs: 4, MemoryMiB: 8192},
+}
+
+func (c *FakeClient) DescribeInstanceType(ctx context.Context, instanceType string) (*InstanceTypeInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("DescribeInstanceType"); err != nil {
+		return nil, fmt.Errorf("failed to describe instance type: %v", err)
+	}
+
+	info, exists := fakeInstanceTypes[instanceType]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownInstanceType, instanceType)
+	}
+
+	info.Name = instanceType
+	info.Available = true
+	return &info, nil
+}
+
+// CreateSnapshot snapshots the root volume, the snapshot completes after the pending delay
+func (c *FakeClient) CreateSnapshot(ctx context.Context, instanceId *string, description string, tags map[string]string) (*AWSSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("CreateSnapshot"); err != nil {
+		return &AWSSnapshot{}, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+
+	if err := c.requireState([]string{*instanceId}); err != nil {
+		return &AWSSnapshot{}, fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	c.nextID++
+	snapshot := &AWSSnapshot{
+		Id:        fmt.Sprintf("snap-fake%013d", c.nextID),
+		State:     SnapshotStatePending,
+		Progress:  "0%",
+		SizeGB:    c.diskSizes[*instanceId],
+		StartTime: time.Now(),
+	}
+	c.snapshots[snapshot.Id] = snapshot
+
+	complete := func() {
+		snapshot.State = SnapshotStateCompleted
+		snapshot.Progress = "100%"
+	}
+	if c.config.PendingDelay <= 0 {
+		complete()
+	} else {
+		c.config.AfterFunc(c.config.PendingDelay, func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			complete()
+		})
+	}
+
+	copied := *snapshot
+	return &copied, nil
+}
+
+func (c *FakeClient) DescribeSnapshots(ctx context.Context, snapshotIds []string) (*[]AWSSnapshot, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("DescribeSnapshots"); err != nil {
+		return nil, fmt.Errorf("failed to describe snapshots: %v", err)
+	}
+
+	snapshots := []AWSSnapshot{}
+	for _, id := range snapshotIds {
+		if snapshot, exists := c.snapshots[id]; exists {
+			snapshots = append(snapshots, *snapshot)
+		}
+	}
+	return &snapshots, nil
+}
+
+func (c *FakeClient) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("DeleteSnapshot"); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %v", err)
+	}
+
+	if _, exists := c.snapshots[snapshotId]; !exists {
+		return fmt.Errorf("failed to delete snapshot: snapshot %s not found", snapshotId)
+	}
+
+	delete(c.snapshots, snapshotId)
+	return nil
+}
+
+func (c *FakeClient) RestoreRootVolume(ctx context.Context, instanceId *string, snapshotId string, sizeGB int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failure("RestoreRootVolume"); err != nil {
+		return fmt.Errorf("failed to create volume: %v", err)
+	}
+
+	if err := c.requireState([]string{*instanceId}, types.InstanceStateNameStopped); err != nil {
+		return fmt.Errorf("instance must be stopped to restore its volume: %v", err)
+	}
+
+	snapshot, exists := c.snapshots[snapshotId]
+	if !exists || snapshot.State != SnapshotStateCompleted {
+		return fmt.Errorf("snapshot %s not found", snapshotId)
+	}
+
+	c.diskSizes[*instanceId] = max(sizeGB, snapshot.SizeGB)
+	return nil
+}
+
+// failure returns the error injected for method, if any. Callers must hold c.mu.
+func (c *FakeClient) failure(method string) error {
+	return c.failures[method]
+}
+
+// requireState checks that all instances exist and, if states are given, are in one of them.
+// Callers must hold c.mu.
+func (c *FakeClient) requireState(instanceIds []string, states ...types.InstanceStateName) error {
+	for _, id := range instanceIds {
+		instance, exists := c.instances[id]
+		if !exists {
+			return fmt.Errorf("instance %s not found", id)
+		}
+
+		if len(states) == 0 {
+			continue
+		}
+
+		allowed := false
+		for _, state := range states {
+			if instance.State == string(state) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("instance %s is in state %s", id, instance.State)
+		}
+	}
+
+	return nil
+}
+
+// transition moves an instance into an intermediate state and schedules the final state
+// after delay. Each transition bumps the generation of the instance, so the timer of an
+// earlier transition cannot finish a later one. Callers must hold c.mu.
+func (c *FakeClient) transition(instanceId string, intermediate, final types.InstanceStateName, delay time.Duration) {
+	instance := c.instances[instanceId]
+	instance.State = string(intermediate)
+	c.generations[instanceId]++
+	generation := c.generations[instanceId]
+
+	complete := func() {
+		// Another transition may have started in the meantime
+		if c.generations[instanceId] != generation {
+			return
+		}
+		instance.State = string(final)
+		if final == types.InstanceStateNameRunning {
+			c.nextIP++
+			instance.PublicIp = fmt.Sprintf("10.0.%d.%d", (c.nextIP/250)%250, c.nextIP%250+1)
+			c.notifyAgent(instanceId, true)
+		}
+	}
+
+	if delay <= 0 {
+		complete()
+		return
+	}
+
+	c.config.AfterFunc(delay, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		complete()
+	})
+}
+
+// notifyAgent tells the agent that the instance booted or is shutting down. The agent runs
+// in its own goroutine since it calls back into the API, which calls the client.
+// Callers must hold c.mu.
+func (c *FakeClient) notifyAgent(instanceId string, startup bool) {
+	if c.config.Agent == nil {
+		return
+	}
+
+	instance := *c.instances[instanceId]
+	agentEnv := c.agentEnvs[instanceId]
+	if startup {
+		go c.config.Agent.Startup(instance, agentEnv)
+	} else {
+		go c.config.Agent.Shutdown(instance, agentEnv)
+	}
+}
@@ -0,0 +1,238 @@
+package instance_aws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func createFakeInstance(t *testing.T, client *FakeClient) *AWSInstance {
+	t.Helper()
+
+	instanceType := AWSInstanceType("t3.small")
+	instance, err := client.CreateInstance(context.Background(), &instanceType, &CreateInstanceOptions{DiskSize: 8})
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+	return instance
+}
+
+func requireFakeState(t *testing.T, client *FakeClient, instanceId string, state types.InstanceStateName) *AWSInstance {
+	t.Helper()
+
+	instance, err := client.GetInstance(context.Background(), &instanceId)
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	if instance.State != string(state) {
+		t.Fatalf("state = %s, want %s", instance.State, state)
+	}
+	return instance
+}
+
+func TestFakeClientLifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := NewFakeClient(FakeClientConfig{})
+	created := createFakeInstance(t, client)
+
+	if created.Tags[TagManaged] != "true" {
+		t.Fatalf("managed tag = %q, want true", created.Tags[TagManaged])
+	}
+
+	running := requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+	if running.PublicIp == "" {
+		t.Fatal("running instance has no public IP")
+	}
+
+	if err := client.StopInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StopInstances: %v", err)
+	}
+	stopped := requireFakeState(t, client, created.Id, types.InstanceStateNameStopped)
+	if stopped.PublicIp != "" {
+		t.Fatalf("stopped instance has public IP %q", stopped.PublicIp)
+	}
+
+	if err := client.StartInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StartInstances: %v", err)
+	}
+	requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+
+	if err := client.TerminateInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("TerminateInstances: %v", err)
+	}
+	requireFakeState(t, client, created.Id, types.InstanceStateNameTerminated)
+
+	managed, err := client.GetManagedInstances(ctx)
+	if err != nil {
+		t.Fatalf("GetManagedInstances: %v", err)
+	}
+	if len(*managed) != 0 {
+		t.Fatalf("managed instances = %d, want 0 after termination", len(*managed))
+	}
+}
+
+func TestFakeClientRejectsIllegalCalls(t *testing.T) {
+	ctx := context.Background()
+	client := NewFakeClient(FakeClientConfig{})
+	created := createFakeInstance(t, client)
+
+	newType := AWSInstanceType("t3.medium")
+	if _, err := client.UpdateInstance(ctx, &created.Id, &newType); err == nil {
+		t.Fatal("UpdateInstance of a running instance succeeded")
+	}
+
+	if err := client.StartInstances(ctx, []string{"i-missing"}); err == nil {
+		t.Fatal("StartInstances of an unknown instance succeeded")
+	}
+
+	if err := client.TerminateInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("TerminateInstances: %v", err)
+	}
+	if err := client.StartInstances(ctx, []string{created.Id}); err == nil {
+		t.Fatal("StartInstances of a terminated instance succeeded")
+	}
+}
+
+func TestFakeClientInjectedFailure(t *testing.T) {
+	ctx := context.Background()
+	client := NewFakeClient(FakeClientConfig{})
+	created := createFakeInstance(t, client)
+
+	injected := errors.New("boom")
+	client.InjectFailure("StopInstances", injected)
+
+	if err := client.StopInstances(ctx, []string{created.Id}); err == nil {
+		t.Fatal("StopInstances succeeded with an injected failure")
+	}
+	requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+
+	client.ClearFailure("StopInstances")
+	if err := client.StopInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StopInstances after ClearFailure: %v", err)
+	}
+	requireFakeState(t, client, created.Id, types.InstanceStateNameStopped)
+}
+
+// fakeTimers collects the delayed transitions of a FakeClient so tests finish them on demand
+type fakeTimers struct {
+	mu      sync.Mutex
+	pending []func()
+}
+
+func (f *fakeTimers) AfterFunc(delay time.Duration, fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(f.pending, fn)
+}
+
+// fireNext runs the oldest scheduled timer
+func (f *fakeTimers) fireNext(t *testing.T) {
+	t.Helper()
+
+	f.mu.Lock()
+	if len(f.pending) == 0 {
+		f.mu.Unlock()
+		t.Fatal("no timer scheduled")
+	}
+	fn := f.pending[0]
+	f.pending = f.pending[1:]
+	f.mu.Unlock()
+
+	fn()
+}
+
+// recordingAgent reports the agent calls of a FakeClient
+type recordingAgent struct {
+	calls chan string
+}
+
+func (a *recordingAgent) Startup(instance AWSInstance, agentEnv map[string]string) {
+	a.calls <- "startup " + instance.Id + " " + instance.PublicIp + " " + agentEnv["GSHUB_INSTANCE_ID"]
+}
+
+func (a *recordingAgent) Shutdown(instance AWSInstance, agentEnv map[string]string) {
+	a.calls <- "shutdown " + instance.Id
+}
+
+func (a *recordingAgent) requireCall(t *testing.T, want string) {
+	t.Helper()
+
+	select {
+	case call := <-a.calls:
+		if call != want {
+			t.Fatalf("agent call = %q, want %q", call, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("agent call %q not made", want)
+	}
+}
+
+func TestFakeClientDelayedTransition(t *testing.T) {
+	timers := &fakeTimers{}
+	client := NewFakeClient(FakeClientConfig{PendingDelay: time.Minute, AfterFunc: timers.AfterFunc})
+	created := createFakeInstance(t, client)
+
+	requireFakeState(t, client, created.Id, types.InstanceStateNamePending)
+
+	timers.fireNext(t)
+	requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+}
+
+func TestFakeClientStaleTimerDoesNotFinishLaterTransition(t *testing.T) {
+	ctx := context.Background()
+	timers := &fakeTimers{}
+	client := NewFakeClient(FakeClientConfig{PendingDelay: time.Minute, AfterFunc: timers.AfterFunc})
+	created := createFakeInstance(t, client)
+
+	// Stopped right away, then started again before the first pending timer fires
+	if err := client.StopInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StopInstances: %v", err)
+	}
+	if err := client.StartInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StartInstances: %v", err)
+	}
+
+	// The timer of the first start must not finish the second one
+	timers.fireNext(t)
+	requireFakeState(t, client, created.Id, types.InstanceStateNamePending)
+
+	timers.fireNext(t)
+	requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+}
+
+func TestFakeClientCallsAgent(t *testing.T) {
+	ctx := context.Background()
+	agent := &recordingAgent{calls: make(chan string, 4)}
+	client := NewFakeClient(FakeClientConfig{Agent: agent})
+
+	instanceType := AWSInstanceType("t3.small")
+	created, err := client.CreateInstance(ctx, &instanceType, &CreateInstanceOptions{
+		AgentEnv: map[string]string{"GSHUB_INSTANCE_ID": "7"},
+		DiskSize: 8,
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance: %v", err)
+	}
+
+	running := requireFakeState(t, client, created.Id, types.InstanceStateNameRunning)
+	agent.requireCall(t, "startup "+created.Id+" "+running.PublicIp+" 7")
+
+	if err := client.StopInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("StopInstances: %v", err)
+	}
+	agent.requireCall(t, "shutdown "+created.Id)
+
+	// A stopped instance has no agent left to shut down
+	if err := client.TerminateInstances(ctx, []string{created.Id}); err != nil {
+		t.Fatalf("TerminateInstances: %v", err)
+	}
+	select {
+	case call := <-agent.calls:
+		t.Fatalf("unexpected agent call %q", call)
+	default:
+	}
+}
@@ -0,0 +1,82 @@
+package instance_aws
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InstanceCache keeps described instances for a short time so listings that are
+// refreshed often do not describe the same instances on every request.
+// It is safe for concurrent use.
+type InstanceCache struct {
+	client  InstanceClient
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]instanceCacheEntry
+}
+
+type instanceCacheEntry struct {
+	instance  AWSInstance
+	found     bool // Unknown instances are cached too
+	fetchedAt time.Time
+}
+
+// NewInstanceCache initializes a cache keeping described instances for ttl
+func NewInstanceCache(client InstanceClient, ttl time.Duration) *InstanceCache {
+	return &InstanceCache{
+		client:  client,
+		ttl:     ttl,
+		entries: make(map[string]instanceCacheEntry),
+	}
+}
+
+// GetInstances returns the instances keyed by id. Instances missing from the cache or expired
+// are described in a single call, ids the provider does not know are left out.
+func (c *InstanceCache) GetInstances(ctx context.Context, instanceIds []string) (map[string]AWSInstance, error) {
+	now := time.Now()
+	result := make(map[string]AWSInstance, len(instanceIds))
+
+	c.mu.Lock()
+	var stale []string
+	for _, id := range instanceIds {
+		entry, cached := c.entries[id]
+		if !cached || now.Sub(entry.fetchedAt) > c.ttl {
+			stale = append(stale, id)
+			continue
+		}
+		if entry.found {
+			result[id] = entry.instance
+		}
+	}
+	c.mu.Unlock()
+
+	if len(stale) == 0 {
+		return result, nil
+	}
+
+	described, err := c.client.GetInstances(ctx, &stale)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range stale {
+		c.entries[id] = instanceCacheEntry{fetchedAt: now}
+	}
+	for _, instance := range *described {
+		c.entries[instance.Id] = instanceCacheEntry{instance: instance, found: true, fetchedAt: now}
+		result[instance.Id] = instance
+	}
+
+	// Drop expired entries so the cache does not grow with terminated instances
+	for id, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > c.ttl {
+			delete(c.entries, id)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package instance_aws
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Processor architectures of instance types, each needs its own image
+const (
+	ArchitectureX86_64 = "x86_64"
+	ArchitectureArm64  = "arm64"
+)
+
+// Families allowed when INSTANCE_TYPE_FAMILIES is not set
+const defaultInstanceTypeFamilies = "t3,t3a,t4g,c7g"
+
+var ErrUnknownInstanceType = errors.New("unknown instance type")
+
+// InstanceTypeInfo describes the capabilities of an instance type in the configured region
+type InstanceTypeInfo struct {
+	Name         string `json:"name"`
+	Architecture string `json:"architecture"`
+	VCPUs        int32  `json:"vCpus"`
+	MemoryMiB    int64  `json:"memoryMiB"`
+	Available    bool   `json:"available"` // Offered in the configured region
+}
+
+// ParseInstanceType checks that the instance type belongs to one of the families
+// listed in INSTANCE_TYPE_FAMILIES (e.g. "t3,t4g,c7g"). Whether the provider offers it
+// is checked with InstanceClient.DescribeInstanceType.
+func ParseInstanceType(instanceType string) (AWSInstanceType, error) {
+	family, size, found := strings.Cut(instanceType, ".")
+	if !found || family == "" || size == "" {
+		return "", errors.New("invalid instance type")
+	}
+
+	if !slices.Contains(instanceTypeFamilies(), family) {
+		return "", fmt.Errorf("instance type family %s is not allowed", family)
+	}
+
+	return AWSInstanceType(instanceType), nil
+}
+
+func instanceTypeFamilies() []string {
+	value := os.Getenv("INSTANCE_TYPE_FAMILIES")
+	if value == "" {
+		value = defaultInstanceTypeFamilies
+	}
+
+	var families []string
+	for _, family := range strings.Split(value, ",") {
+		if family = strings.TrimSpace(family); family != "" {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// imageForArchitecture returns the base image for the architecture,
+// AWS_IMAGE_ID_BASE for x86_64 and AWS_IMAGE_ID_BASE_ARM64 for arm64
+func imageForArchitecture(architecture string) (string, error) {
+	var imageId string
+	switch architecture {
+	case ArchitectureX86_64:
+		imageId = os.Getenv("AWS_IMAGE_ID_BASE")
+	case ArchitectureArm64:
+		imageId = os.Getenv("AWS_IMAGE_ID_BASE_ARM64")
+	default:
+		return "", fmt.Errorf("unsupported architecture %s", architecture)
+	}
+
+	if imageId == "" {
+		return "", fmt.Errorf("no base image configured for %s", architecture)
+	}
+	return imageId, nil
+}
@@ -0,0 +1,55 @@
+package instance_aws
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+)
+
+// InstanceClient is the set of cloud provider operations used to manage instances.
+// AWSClient implements it against EC2/SSM and FakeClient implements it in memory.
+type InstanceClient interface {
+	CreateInstance(ctx context.Context, instanceType *AWSInstanceType, options *CreateInstanceOptions) (*AWSInstance, error)
+	GetInstance(ctx context.Context, instanceId *string) (*AWSInstance, error)
+	GetInstances(ctx context.Context, instanceIds *[]string) (*[]AWSInstance, error)
+	GetManagedInstances(ctx context.Context) (*[]AWSInstance, error)
+	GetRunningInstances(ctx context.Context) (*[]string, error)
+	UpdateInstance(ctx context.Context, instanceId *string, newInstanceType *AWSInstanceType) (*AWSInstance, error)
+	ResizeRootVolume(ctx context.Context, instanceId *string, sizeGB int32) error
+	StartInstances(ctx context.Context, instanceIds []string) error
+	StopInstances(ctx context.Context, instanceIds []string) error
+	TerminateInstances(ctx context.Context, instanceIds []string) error
+	SendCommand(ctx context.Context, command *string, instanceIds *[]string) error
+	DescribeInstanceType(ctx context.Context, instanceType string) (*InstanceTypeInfo, error)
+	CreateSnapshot(ctx context.Context, instanceId *string, description string, tags map[string]string) (*AWSSnapshot, error)
+	DescribeSnapshots(ctx context.Context, snapshotIds []string) (*[]AWSSnapshot, error)
+	DeleteSnapshot(ctx context.Context, snapshotId string) error
+	RestoreRootVolume(ctx context.Context, instanceId *string, snapshotId string, sizeGB int32) error
+}
+
+var (
+	_ InstanceClient = (*AWSClient)(nil)
+	_ InstanceClient = (*FakeClient)(nil)
+)
+
+// NewInstanceClient returns the provider selected by INSTANCE_PROVIDER ("aws" or "fake").
+// The fake provider uses FAKE_PROVIDER_DELAY (e.g. "5s") for every state transition and
+// makes the agent callbacks of its instances itself.
+func NewInstanceClient() InstanceClient {
+	switch strings.ToLower(os.Getenv("INSTANCE_PROVIDER")) {
+	case "fake":
+		delay, err := time.ParseDuration(os.Getenv("FAKE_PROVIDER_DELAY"))
+		if err != nil {
+			delay = 0
+		}
+		return NewFakeClient(FakeClientConfig{
+			PendingDelay:      delay,
+			StoppingDelay:     delay,
+			ShuttingDownDelay: delay,
+			Agent:             NewCallbackAgent(),
+		})
+	default:
+		return NewAWSClient()
+	}
+}
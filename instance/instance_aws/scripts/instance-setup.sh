@@ -40,7 +40,7 @@ if sudo docker ps -a --format '{{.Names}}' | grep -Eq "^api\$"; then
 fi
 
 # Run the application
-sudo docker run --restart always -d -p 3001:3001 --name api -v /var/run/docker.sock:/var/run/docker.sock -e INSTANCE_ID="$INSTANCE_ID" -e APP_ENV="production" dasior/server-api
+sudo docker run --restart always -d -p 3001:3001 --name api -v /var/run/docker.sock:/var/run/docker.sock --env-file /etc/gshub/agent.env -e INSTANCE_ID="$INSTANCE_ID" -e APP_ENV="production" dasior/server-api
 EOF
 
 # Make the startup script executable
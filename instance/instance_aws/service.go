@@ -8,11 +8,11 @@ import (
 
 // Service provides instance management functionality
 type AWSService struct {
-	client *AWSClient
+	client InstanceClient
 }
 
 // NewService initializes a new instance of Service
-func NewService(client *AWSClient) *AWSService {
+func NewService(client InstanceClient) *AWSService {
 	return &AWSService{client: client}
 }
 
@@ -0,0 +1,269 @@
+package instance_aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Snapshot states reported in AWSSnapshot.State
+const (
+	SnapshotStatePending   = string(types.SnapshotStatePending)
+	SnapshotStateCompleted = string(types.SnapshotStateCompleted)
+	SnapshotStateError     = string(types.SnapshotStateError)
+)
+
+// How long a restore waits for the volumes or images to become available
+const volumeWaitTimeout = 10 * time.Minute
+
+type AWSSnapshot struct {
+	Id        string    `json:"-"`
+	State     string    `json:"state"`
+	Progress  string    `json:"progress"` // Percentage, e.g. "42%"
+	SizeGB    int32     `json:"sizeGb"`   // Size of the snapshotted volume
+	StartTime time.Time `json:"startTime"`
+}
+
+// CreateSnapshot snapshots the root volume of the instance. The snapshot is pending until
+// DescribeSnapshots reports it completed.
+func (c *AWSClient) CreateSnapshot(ctx context.Context, instanceId *string, description string, tags map[string]string) (*AWSSnapshot, error) {
+	_, volumeId, err := c.rootVolume(ctx, instanceId)
+	if err != nil {
+		return &AWSSnapshot{}, err
+	}
+
+	snapshotTags := []types.Tag{{Key: aws.String(TagManaged), Value: aws.String("true")}}
+	for key, value := range tags {
+		snapshotTags = append(snapshotTags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	result, err := c.ec2.CreateSnapshot(ctx, &ec2.CreateSnapshotInput{
+		VolumeId:    volumeId,
+		Description: aws.String(description),
+		TagSpecifications: []types.TagSpecification{
+			{ResourceType: types.ResourceTypeSnapshot, Tags: snapshotTags},
+		},
+	})
+	if err != nil {
+		return &AWSSnapshot{}, fmt.Errorf("failed to create snapshot: %v", err)
+	}
+
+	return &AWSSnapshot{
+		Id:        aws.ToString(result.SnapshotId),
+		State:     string(result.State),
+		Progress:  aws.ToString(result.Progress),
+		SizeGB:    aws.ToInt32(result.VolumeSize),
+		StartTime: aws.ToTime(result.StartTime),
+	}, nil
+}
+
+// DescribeSnapshots describes the given snapshots. Snapshots that do not exist are left out.
+func (c *AWSClient) DescribeSnapshots(ctx context.Context, snapshotIds []string) (*[]AWSSnapshot, error) {
+	snapshots := []AWSSnapshot{}
+
+	for _, snapshotId := range snapshotIds {
+		// One call per snapshot, a missing snapshot fails the whole call
+		result, err := c.ec2.DescribeSnapshots(ctx, &ec2.DescribeSnapshotsInput{SnapshotIds: []string{snapshotId}})
+		if err != nil {
+			var apiErr interface{ ErrorCode() string }
+			if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidSnapshot.NotFound" {
+				continue
+			}
+			return nil, fmt.Errorf("failed to describe snapshots: %v", err)
+		}
+
+		for _, snapshot := range result.Snapshots {
+			snapshots = append(snapshots, AWSSnapshot{
+				Id:        aws.ToString(snapshot.SnapshotId),
+				State:     string(snapshot.State),
+				Progress:  aws.ToString(snapshot.Progress),
+				SizeGB:    aws.ToInt32(snapshot.VolumeSize),
+				StartTime: aws.ToTime(snapshot.StartTime),
+			})
+		}
+	}
+
+	return &snapshots, nil
+}
+
+func (c *AWSClient) DeleteSnapshot(ctx context.Context, snapshotId string) error {
+	_, err := c.ec2.DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(snapshotId)})
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshot: %v", err)
+	}
+	return nil
+}
+
+// RestoreRootVolume replaces the root volume of a stopped instance with a new volume created from
+// the snapshot, at least sizeGB large. The replaced volume is deleted. It blocks until the new
+// volume is attached. Until then every failure puts the previous volume back, afterwards cleanup
+// failures are only logged since the instance already runs from the restored volume.
+func (c *AWSClient) RestoreRootVolume(ctx context.Context, instanceId *string, snapshotId string, sizeGB int32) error {
+	instance, oldVolumeId, err := c.rootVolume(ctx, instanceId)
+	if err != nil {
+		return err
+	}
+
+	if instance.State == nil || instance.State.Name != types.InstanceStateNameStopped {
+		return errors.New("instance must be stopped to restore its volume")
+	}
+
+	volumeId, err := c.CreateVolume(ctx, snapshotId, aws.ToString(instance.Placement.AvailabilityZone), sizeGB)
+	if err != nil {
+		return err
+	}
+
+	// rollback reattaches the previous volume and deletes the unused new one
+	rollback := func() {
+		_, err := c.ec2.AttachVolume(ctx, &ec2.AttachVolumeInput{VolumeId: oldVolumeId, InstanceId: instanceId, Device: instance.RootDeviceName})
+		if err != nil {
+			log.Printf("%s: Failed to reattach volume %s: %v", *instanceId, *oldVolumeId, err)
+		}
+		if _, err := c.ec2.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: aws.String(volumeId)}); err != nil {
+			log.Printf("%s: Failed to delete volume %s: %v", *instanceId, volumeId, err)
+		}
+	}
+
+	if _, err := c.ec2.DetachVolume(ctx, &ec2.DetachVolumeInput{VolumeId: oldVolumeId, InstanceId: instanceId}); err != nil {
+		c.ec2.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: aws.String(volumeId)})
+		return fmt.Errorf("failed to detach volume: %v", err)
+	}
+
+	waiter := ec2.NewVolumeAvailableWaiter(c.ec2)
+	if err := waiter.Wait(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{*oldVolumeId}}, volumeWaitTimeout); err != nil {
+		rollback()
+		return fmt.Errorf("failed to wait for volume detachment: %v", err)
+	}
+
+	_, err = c.ec2.AttachVolume(ctx, &ec2.AttachVolumeInput{
+		VolumeId:   aws.String(volumeId),
+		InstanceId: instanceId,
+		Device:     instance.RootDeviceName,
+	})
+	if err != nil {
+		rollback()
+		return fmt.Errorf("failed to attach volume: %v", err)
+	}
+
+	// The new volume is deleted with the instance like the original one
+	_, err = c.ec2.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: instanceId,
+		BlockDeviceMappings: []types.InstanceBlockDeviceMappingSpecification{{
+			DeviceName: instance.RootDeviceName,
+			Ebs:        &types.EbsInstanceBlockDeviceSpecification{DeleteOnTermination: aws.Bool(true)},
+		}},
+	})
+	if err != nil {
+		log.Printf("%s: Failed to set deletion on termination of volume %s: %v", *instanceId, volumeId, err)
+	}
+
+	if _, err := c.ec2.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: oldVolumeId}); err != nil {
+		log.Printf("%s: Failed to delete replaced volume %s: %v", *instanceId, *oldVolumeId, err)
+	}
+
+	return nil
+}
+
+// CreateVolume creates a volume from the snapshot in the availability zone and waits until it
+// is available. The volume is at least sizeGB large, or the size of the snapshot if larger.
+func (c *AWSClient) CreateVolume(ctx context.Context, snapshotId string, availabilityZone string, sizeGB int32) (string, error) {
+	input := &ec2.CreateVolumeInput{
+		SnapshotId:       aws.String(snapshotId),
+		AvailabilityZone: aws.String(availabilityZone),
+		VolumeType:       types.VolumeTypeGp3,
+		TagSpecifications: []types.TagSpecification{
+			{ResourceType: types.ResourceTypeVolume, Tags: []types.Tag{{Key: aws.String(TagManaged), Value: aws.String("true")}}},
+		},
+	}
+
+	snapshots, err := c.DescribeSnapshots(ctx, []string{snapshotId})
+	if err != nil {
+		return "", err
+	}
+	if len(*snapshots) == 0 {
+		return "", fmt.Errorf("snapshot %s not found", snapshotId)
+	}
+	if sizeGB > (*snapshots)[0].SizeGB {
+		input.Size = aws.Int32(sizeGB)
+	}
+
+	result, err := c.ec2.CreateVolume(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume: %v", err)
+	}
+	volumeId := aws.ToString(result.VolumeId)
+
+	waiter := ec2.NewVolumeAvailableWaiter(c.ec2)
+	if err := waiter.Wait(ctx, &ec2.DescribeVolumesInput{VolumeIds: []string{volumeId}}, volumeWaitTimeout); err != nil {
+		c.ec2.DeleteVolume(ctx, &ec2.DeleteVolumeInput{VolumeId: aws.String(volumeId)})
+		return "", fmt.Errorf("failed to wait for volume: %v", err)
+	}
+
+	return volumeId, nil
+}
+
+// registerSnapshotImage registers an image like baseImageId whose root volume is created from the snapshot
+// and waits until it is available. The caller deregisters it once the instance is launched.
+func (c *AWSClient) registerSnapshotImage(ctx context.Context, baseImageId string, snapshotId string, architecture string) (string, error) {
+	images, err := c.ec2.DescribeImages(ctx, &ec2.DescribeImagesInput{ImageIds: []string{baseImageId}})
+	if err != nil || len(images.Images) == 0 {
+		return "", fmt.Errorf("failed to describe image: %v", err)
+	}
+	base := images.Images[0]
+
+	result, err := c.ec2.RegisterImage(ctx, &ec2.RegisterImageInput{
+		Name:               aws.String(fmt.Sprintf("gshub-restore-%s-%d", snapshotId, time.Now().Unix())),
+		Architecture:       types.ArchitectureValues(architecture),
+		RootDeviceName:     base.RootDeviceName,
+		VirtualizationType: aws.String(string(types.VirtualizationTypeHvm)),
+		EnaSupport:         aws.Bool(true),
+		BlockDeviceMappings: []types.BlockDeviceMapping{
+			{
+				DeviceName: base.RootDeviceName,
+				Ebs: &types.EbsBlockDevice{
+					SnapshotId:          aws.String(snapshotId),
+					VolumeType:          types.VolumeTypeGp3,
+					DeleteOnTermination: aws.Bool(true),
+				},
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to register image: %v", err)
+	}
+	imageId := aws.ToString(result.ImageId)
+
+	waiter := ec2.NewImageAvailableWaiter(c.ec2)
+	if err := waiter.Wait(ctx, &ec2.DescribeImagesInput{ImageIds: []string{imageId}}, volumeWaitTimeout); err != nil {
+		c.ec2.DeregisterImage(ctx, &ec2.DeregisterImageInput{ImageId: aws.String(imageId)})
+		return "", fmt.Errorf("failed to wait for image: %v", err)
+	}
+
+	return imageId, nil
+}
+
+// rootVolume describes the instance and returns the ID of its root volume
+func (c *AWSClient) rootVolume(ctx context.Context, instanceId *string) (types.Instance, *string, error) {
+	result, err := c.ec2.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{*instanceId},
+	})
+	if err != nil || len(result.Reservations) == 0 {
+		return types.Instance{}, nil, fmt.Errorf("failed to describe instance: %v", err)
+	}
+
+	instance := result.Reservations[0].Instances[0]
+
+	for _, mapping := range instance.BlockDeviceMappings {
+		if aws.ToString(mapping.DeviceName) == aws.ToString(instance.RootDeviceName) && mapping.Ebs != nil && mapping.Ebs.VolumeId != nil {
+			return instance, mapping.Ebs.VolumeId, nil
+		}
+	}
+
+	return instance, nil, errors.New("instance has no root volume")
+}
@@ -0,0 +1,101 @@
+package instance_billing
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/plan/plan_models"
+)
+
+const defaultCyclesPerPriceUnit = 100
+
+// MeterResult describes one metering pass over an instance
+type MeterResult struct {
+	Burned           int64   `json:"burned"`           // Cycles burned by this pass
+	Balance          int64   `json:"balance"`          // Cycles left afterwards
+	CyclesPerHour    float64 `json:"cyclesPerHour"`    // Burn rate of the instance's plan
+	MinutesRemaining float64 `json:"minutesRemaining"` // Running time the balance pays for, 0 on free plans
+}
+
+// MeterInstance burns the cycles used since the instance was last metered.
+// Only whole cycles are burned, the remainder is carried over to the next pass.
+func MeterInstance(appCtx *app.Context, instance *instance_models.Instance, now time.Time) (*MeterResult, error) {
+	plan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan: %v", err)
+	}
+
+	account := FundingAccount(instance)
+	rate := CyclesPerHour(plan)
+	result := &MeterResult{CyclesPerHour: rate}
+
+	if instance.MeteredAt != nil && rate > 0 {
+		cycles := uint(now.Sub(*instance.MeteredAt).Hours() * rate)
+		if cycles > 0 {
+			// Keyed on the metering start so a retry after a failed save cannot burn twice
+			transaction, err := appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+				From:           account,
+				To:             ledger_repositories.SystemAccount(ledger_models.SystemAccountBurned),
+				Amount:         cycles,
+				Reason:         ledger_models.ReasonBurn,
+				Description:    fmt.Sprintf("Running time on %s", plan.Name),
+				Actor:          "metering",
+				UserID:         instance.UserID,
+				InstanceID:     instance.ID,
+				IdempotencyKey: fmt.Sprintf("meter:%d:%d", instance.ID, instance.MeteredAt.UnixNano()),
+				ClampToBalance: true,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to burn cycles: %v", err)
+			}
+
+			// metered_at only moves over the time that was paid for, the rest is burned by a
+			// later pass once the funding account is topped up. An empty account moves nothing.
+			var burned uint
+			if transaction != nil {
+				result.Burned = transaction.Credited()
+				burned = uint(result.Burned)
+			}
+			// Left alone when the shutdown or another pass moved it meanwhile, the burn is keyed on it
+			meteredAt := instance.MeteredAt.Add(time.Duration(float64(burned) / rate * float64(time.Hour)))
+			if _, err := appCtx.InstanceRepository.UpdateInstanceMeteredAt(instance, &meteredAt); err != nil {
+				return nil, fmt.Errorf("failed to update metering: %v", err)
+			}
+		}
+	}
+
+	result.Balance, err = appCtx.LedgerRepository.GetBalance(account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %v", err)
+	}
+
+	if rate > 0 {
+		result.MinutesRemaining = float64(result.Balance) / rate * 60
+	}
+
+	return result, nil
+}
+
+// FundingAccount is the account the instance burns cycles from, its wallet's if it has one
+func FundingAccount(instance *instance_models.Instance) ledger_repositories.AccountRef {
+	if instance.WalletID != nil {
+		return ledger_repositories.WalletAccount(*instance.WalletID)
+	}
+	return ledger_repositories.InstanceAccount(instance.ID)
+}
+
+// CyclesPerHour is the burn rate of a plan. One unit of the plan's hourly price
+// costs CYCLES_PER_PRICE_UNIT cycles.
+func CyclesPerHour(plan *plan_models.Plan) float64 {
+	cyclesPerUnit, err := strconv.ParseFloat(os.Getenv("CYCLES_PER_PRICE_UNIT"), 64)
+	if err != nil || cyclesPerUnit <= 0 {
+		cyclesPerUnit = defaultCyclesPerPriceUnit
+	}
+	return plan.Price * cyclesPerUnit
+}
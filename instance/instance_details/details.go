@@ -0,0 +1,90 @@
+package instance_details
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/plan/plan_models"
+)
+
+// InstanceDetails is an instance record with its live provider state
+type InstanceDetails struct {
+	instance_models.Instance
+	Live *LiveDetails `json:"live"` // nil when the provider does not know the instance
+}
+
+// LiveDetails is the state of an instance as reported by the provider
+type LiveDetails struct {
+	State          string    `json:"state"`
+	Type           string    `json:"type"`
+	PublicIP       string    `json:"publicIp"`
+	LaunchTime     time.Time `json:"launchTime"`
+	UptimeSeconds  int64     `json:"uptimeSeconds"`  // Time since launch while running, 0 otherwise
+	CyclesPerHour  float64   `json:"cyclesPerHour"`  // Burn rate while metered
+	UnbilledCycles float64   `json:"unbilledCycles"` // Cycles accrued since the last metering pass
+}
+
+// Enrich adds the live provider state to the instances using the cached batch describe.
+// When the provider cannot be reached the instances are returned without live state
+// along with the error.
+func Enrich(ctx context.Context, appCtx *app.Context, instances []instance_models.Instance) ([]InstanceDetails, error) {
+	details := make([]InstanceDetails, len(instances))
+	var realIDs []string
+	for i, instance := range instances {
+		details[i].Instance = instance
+		if instance.RealID != "" {
+			realIDs = append(realIDs, instance.RealID)
+		}
+	}
+
+	if len(realIDs) == 0 {
+		return details, nil
+	}
+
+	live, err := appCtx.InstanceCache.GetInstances(ctx, realIDs)
+	if err != nil {
+		return details, err
+	}
+
+	plans, err := appCtx.PlanRepository.GetPlans()
+	if err != nil {
+		return details, err
+	}
+	planByID := make(map[uint]plan_models.Plan, len(*plans))
+	for _, plan := range *plans {
+		planByID[plan.ID] = plan
+	}
+
+	now := time.Now()
+	for i := range details {
+		awsInstance, found := live[details[i].RealID]
+		if !found {
+			continue
+		}
+
+		liveDetails := &LiveDetails{
+			State:      awsInstance.State,
+			Type:       awsInstance.Type,
+			PublicIP:   awsInstance.PublicIp,
+			LaunchTime: awsInstance.LaunchTime,
+		}
+		if awsInstance.State == string(types.InstanceStateNameRunning) {
+			liveDetails.UptimeSeconds = int64(now.Sub(awsInstance.LaunchTime).Seconds())
+		}
+
+		if meteredAt := details[i].MeteredAt; meteredAt != nil {
+			if plan, ok := planByID[details[i].PlanID]; ok {
+				liveDetails.CyclesPerHour = instance_billing.CyclesPerHour(&plan)
+				liveDetails.UnbilledCycles = now.Sub(*meteredAt).Hours() * liveDetails.CyclesPerHour
+			}
+		}
+
+		details[i].Live = liveDetails
+	}
+
+	return details, nil
+}
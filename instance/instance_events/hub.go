@@ -0,0 +1,192 @@
+package instance_events
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventStatus       EventType = "status"       // The instance moved to another status
+	EventIP           EventType = "ip"           // The public IP of the instance changed
+	EventNotification EventType = "notification" // A notification about the instance was created, e.g. low cycles
+	EventHealth       EventType = "health"       // The agent stopped or resumed sending heartbeats
+)
+
+const (
+	// Number of past events kept for clients reconnecting with Last-Event-ID
+	historySize = 1000
+
+	// Events buffered per subscriber before it is dropped as too slow
+	subscriberBuffer = 64
+
+	// Published events waiting for their audience before Publish blocks
+	publishBuffer = 1024
+)
+
+// StatusData is the data of EventStatus
+type StatusData struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Ready  bool   `json:"ready"`
+	Actor  string `json:"actor"`
+	Reason string `json:"reason"`
+}
+
+// IPData is the data of EventIP, PublicIP is empty when the address was released
+type IPData struct {
+	PublicIP string `json:"publicIp"`
+}
+
+// HealthData is the data of EventHealth
+type HealthData struct {
+	Health string `json:"health"`
+	Reason string `json:"reason"`
+}
+
+// Event is a change of an instance delivered to its owner
+type Event struct {
+	ID         uint64      `json:"id"`
+	Type       EventType   `json:"type"`
+	UserIDs    []uint      `json:"-"` // The owner and the members of the instance
+	InstanceID uint        `json:"instanceId"`
+	Data       interface{} `json:"data"`
+	CreatedAt  time.Time   `json:"createdAt"`
+}
+
+// Hub is an in-process publish/subscribe hub for instance events. It is safe for concurrent use.
+// Events are delivered in publish order by a single dispatcher goroutine.
+type Hub struct {
+	// Audience returns the users besides the owner that receive the events of an instance, may be nil.
+	// It is called by the dispatcher, never by Publish.
+	Audience func(instanceID uint) []uint
+
+	queue       chan Event
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	userID uint
+	events chan Event
+}
+
+// NewHub initializes an empty hub. IDs start at the current time in microseconds so
+// IDs received from an earlier process are recognized as too old to replay.
+func NewHub() *Hub {
+	h := &Hub{
+		queue:       make(chan Event, publishBuffer),
+		lastID:      uint64(time.Now().UnixMicro()),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+	go h.dispatch()
+	return h
+}
+
+// Publish queues the event for the subscribers of the owner and the rest of the audience
+// of the instance. It does not touch the database, so it may be called while a transaction
+// is open. A nil hub discards events.
+func (h *Hub) Publish(userID uint, instanceID uint, eventType EventType, data interface{}) {
+	if h == nil {
+		return
+	}
+
+	h.queue <- Event{
+		Type:       eventType,
+		UserIDs:    []uint{userID},
+		InstanceID: instanceID,
+		Data:       data,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// dispatch resolves the audience of queued events and delivers them. It never returns.
+func (h *Hub) dispatch() {
+	for event := range h.queue {
+		if h.Audience != nil {
+			event.UserIDs = append(event.UserIDs, h.Audience(event.InstanceID)...)
+		}
+		h.deliver(event)
+	}
+}
+
+// deliver numbers the event, keeps it for replays and sends it to its subscribers
+func (h *Hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event.ID = h.lastID
+
+	h.history = append(h.history, event)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	for sub := range h.subscribers {
+		if !event.isFor(sub.userID) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			// Too slow, the client reconnects and replays from its last event
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Subscribe returns the user's events published after lastEventID followed by a channel of
+// new events. When some of the missed events are no longer kept, or were published by an
+// earlier process, no events are returned and resetID is the ID of the latest event: the
+// client has to reload its state and continue from there. Otherwise resetID is 0. The channel
+// is closed when the subscriber falls behind or cancel is called.
+func (h *Hub) Subscribe(userID uint, lastEventID uint64) (missed []Event, resetID uint64, events <-chan Event, cancel func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID > 0 {
+		oldest := h.lastID + 1
+		if len(h.history) > 0 {
+			oldest = h.history[0].ID
+		}
+
+		if lastEventID > h.lastID || lastEventID < oldest-1 {
+			resetID = h.lastID
+		} else {
+			for _, event := range h.history {
+				if event.ID > lastEventID && event.isFor(userID) {
+					missed = append(missed, event)
+				}
+			}
+		}
+	}
+
+	sub := &subscriber{userID: userID, events: make(chan Event, subscriberBuffer)}
+	h.subscribers[sub] = struct{}{}
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, subscribed := h.subscribers[sub]; subscribed {
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	return missed, resetID, sub.events, cancel
+}
+
+func (e Event) isFor(userID uint) bool {
+	for _, id := range e.UserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
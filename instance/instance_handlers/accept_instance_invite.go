@@ -0,0 +1,39 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// AcceptInstanceInvite makes the user a member of the instance of the invite link's token
+func AcceptInstanceInvite(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get user", err, userEmail)
+		return
+	}
+
+	member, err := appCtx.InstanceMemberRepository.AcceptInstanceInvite(c.Param("token"), user)
+	if err != nil {
+		switch {
+		case errors.Is(err, instance_repositories.ErrInviteInvalid):
+			utils.HandleError(c, http.StatusNotFound, "Invite is invalid or expired", err, userEmail)
+		case errors.Is(err, instance_repositories.ErrInviteEmailMismatch):
+			utils.HandleError(c, http.StatusForbidden, "Invite was sent to another email", err, userEmail)
+		case errors.Is(err, instance_repositories.ErrInviteOwner):
+			utils.HandleError(c, http.StatusConflict, "You already own this instance", err, userEmail)
+		default:
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to accept invite", err, userEmail)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
@@ -0,0 +1,20 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// handleInstanceAccessError responds with 403 Forbidden when the user's role on the instance
+// is too low and with 404 Not Found when the user has no access to it.
+func handleInstanceAccessError(c *gin.Context, err error, userEmail string) {
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+}
@@ -0,0 +1,72 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// ArchiveInstance moves the user's stopped instance to cold storage. Its disk is snapshotted and
+// the EC2 instance is terminated once the snapshot completes, leaving only the snapshot to pay for.
+func ArchiveInstance(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if err := instance_lifecycle.ArchiveInstance(c, appCtx, instance, userEmail, "archived by user"); err != nil {
+		if errors.Is(err, backup_lifecycle.ErrBackupUnavailable) {
+			utils.HandleError(c, http.StatusConflict, "Instance cannot be archived in its current status", err, userEmail)
+			return
+		}
+		handleLifecycleError(c, http.StatusInternalServerError, "Unable to archive instance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, instance)
+}
+
+// UnarchiveInstance recreates the user's archived instance with its plan and the archived disk.
+// The instance provisions like a new one.
+func UnarchiveInstance(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if err := instance_lifecycle.UnarchiveInstance(c, appCtx, instance, userEmail); err != nil {
+		handleLifecycleError(c, http.StatusBadRequest, "Unable to unarchive instance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, instance)
+}
@@ -0,0 +1,158 @@
+package instance_handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/plan/plan_validation"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for changing the plan of an instance
+type ChangeInstancePlanRequestBody struct {
+	PlanID uint `json:"planId" binding:"required"`
+}
+
+// ChangeInstancePlan moves a stopped instance to another plan.
+//
+// The root volume is grown when the new plan has a bigger disk; plans with a smaller disk
+// are rejected because volumes cannot shrink. The instance must be stopped, otherwise
+// 409 Conflict is returned.
+func ChangeInstancePlan(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request ChangeInstancePlanRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	if instance.PlanID == request.PlanID {
+		utils.HandleError(c, http.StatusBadRequest, "Instance is already on this plan", errors.New("no changes"), userEmail)
+		return
+	}
+
+	currentPlan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Plan not found", err, userEmail)
+		return
+	}
+
+	newPlan, err := appCtx.PlanRepository.GetPlan(request.PlanID)
+	if err != nil || !newPlan.Enabled {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan", err, userEmail)
+		return
+	}
+
+	if newPlan.Disk < currentPlan.Disk {
+		utils.HandleError(c, http.StatusBadRequest, "Disk cannot be smaller than the current plan's", fmt.Errorf("disk %d GB below %d GB", newPlan.Disk, currentPlan.Disk), userEmail)
+		return
+	}
+
+	// Make sure the new plan can still run the service
+	service, err := appCtx.ServiceRepository.GetService(instance.ServiceID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Service not found", err, userEmail)
+		return
+	}
+
+	preset, err := appCtx.ServiceRepository.GetInstancePreset(service.ID, instance.ServicePresetID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, userEmail)
+		return
+	}
+
+	if newPlan.Memory < preset.Config.MinMem {
+		utils.HandleError(c, http.StatusBadRequest, "Plan does not have enough memory for this service", errors.New("plan memory below service minimum"), userEmail)
+		return
+	}
+
+	instanceType, err := instance_aws.ParseInstanceType(newPlan.InstanceType)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance type", err, userEmail)
+		return
+	}
+
+	// The root volume holds an image for one architecture, it cannot move between them
+	newTypeInfo, err := plan_validation.ValidatePlan(c, appCtx.InstanceClient, newPlan)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan", err, userEmail)
+		return
+	}
+
+	currentTypeInfo, err := appCtx.InstanceClient.DescribeInstanceType(c, currentPlan.InstanceType)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Unable to describe instance type", err, userEmail)
+		return
+	}
+
+	if currentTypeInfo.Architecture != newTypeInfo.Architecture {
+		utils.HandleError(c, http.StatusBadRequest, "Plan uses a different processor architecture", fmt.Errorf("%s to %s", currentTypeInfo.Architecture, newTypeInfo.Architecture), userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusResizing, userEmail, fmt.Sprintf("plan change to %s", newPlan.Name)); err != nil {
+		handleLifecycleError(c, http.StatusBadRequest, "Unable to change plan", err, userEmail)
+		return
+	}
+
+	// Grow the disk first, a bigger disk than the plan's is harmless if the type change fails
+	if newPlan.Disk > currentPlan.Disk {
+		if err := appCtx.InstanceClient.ResizeRootVolume(c, &instance.RealID, int32(newPlan.Disk)); err != nil {
+			appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, userEmail, "disk resize failed")
+			utils.HandleError(c, http.StatusBadRequest, "Unable to resize disk", err, userEmail)
+			return
+		}
+	}
+
+	// Plans sharing the instance type only differ in disk or price, the provider rejects a no-op change
+	if newPlan.InstanceType != currentPlan.InstanceType {
+		if _, err := appCtx.InstanceClient.UpdateInstance(c, &instance.RealID, &instanceType); err != nil {
+			appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, userEmail, "instance type change failed")
+			utils.HandleError(c, http.StatusBadRequest, "Unable to change instance type", err, userEmail)
+			return
+		}
+	}
+
+	// Billing uses the plan's price from now on
+	change := instance_models.InstancePlanChange{
+		InstanceID: instance.ID,
+		FromPlanID: currentPlan.ID,
+		ToPlanID:   newPlan.ID,
+		FromPrice:  currentPlan.Price,
+		ToPrice:    newPlan.Price,
+		Actor:      userEmail,
+	}
+	if err := appCtx.InstanceRepository.ChangeInstancePlan(instance, &change); err != nil {
+		appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusFailed, userEmail, "failed to save plan change")
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save plan change", err, userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, userEmail, "plan changed"); err != nil {
+		handleLifecycleError(c, http.StatusInternalServerError, "Failed to update instance status", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
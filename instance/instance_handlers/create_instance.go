@@ -1,19 +1,26 @@
 package instance_handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
 	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
 
 // The payload for creating an instance
 type CreateInstanceRequestBody struct {
-	PlanID    uint `json:"planId" binding:"required"`
-	ServiceID uint `json:"serviceId" binding:"required"`
+	PlanID      uint              `json:"planId" binding:"required"`
+	ServiceID   uint              `json:"serviceId" binding:"required"`
+	Env         map[string]string `json:"env"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Tags        []string          `json:"tags"`
 }
 
 // CreateInstance creates a new instance and associates it with the user, plan, and service.
@@ -28,6 +35,23 @@ func CreateInstance(c *gin.Context, appCtx *app.Context) {
 
 	userEmail := c.GetString("userEmail")
 
+	name, err := normalizeInstanceName(request.Name)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid name", err, userEmail)
+		return
+	}
+
+	if err := validateInstanceDescription(request.Description); err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid description", err, userEmail)
+		return
+	}
+
+	tags, err := normalizeInstanceTags(request.Tags)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid tags", err, userEmail)
+		return
+	}
+
 	// Get User
 	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
 	if err != nil {
@@ -42,29 +66,95 @@ func CreateInstance(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
+	if !plan.Enabled {
+		utils.HandleError(c, http.StatusBadRequest, "Plan is not available", errors.New("plan is disabled"), userEmail)
+		return
+	}
+
+	// Get Service
+	service, err := appCtx.ServiceRepository.GetService(request.ServiceID)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service", err, userEmail)
+		return
+	}
+
+	// New instances use the published preset version
+	preset, err := appCtx.ServiceRepository.GetPublishedPreset(service.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, userEmail)
+		return
+	}
+	config := preset.Config
+
+	// Make sure the plan can run the service
+	if plan.Memory < config.MinMem {
+		utils.HandleError(c, http.StatusBadRequest, "Plan does not have enough memory for this service", errors.New("plan memory below service minimum"), userEmail)
+		return
+	}
+
+	env, err := config.ResolveEnv(request.Env)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service env", err, userEmail)
+		return
+	}
+
 	instanceType, err := instance_aws.ParseInstanceType(plan.InstanceType)
 	if err != nil {
 		utils.HandleError(c, http.StatusBadRequest, "Invalid instance type", err, userEmail)
 		return
 	}
 
-	ec2Instance, err := appCtx.InstanceClient.CreateInstance(c, &instanceType)
+	instance := instance_models.Instance{
+		PlanID:          plan.ID,
+		UserID:          user.ID,
+		ServiceID:       service.ID,
+		ServicePresetID: preset.ID,
+		RealID:          "",
+		Status:          instance_models.InstanceStatusProvisioning,
+		Ready:           false,
+		Name:            name,
+		Description:     request.Description,
+		PublicIP:        "",
+	}
+
+	for _, tag := range tags {
+		instance.Tags = append(instance.Tags, instance_models.InstanceTag{Tag: tag})
+	}
+
+	for key, value := range env {
+		instance.Env = append(instance.Env, instance_models.InstanceEnv{Key: key, Value: value})
+	}
+
+	// The record is created first so its ID and credential can be handed to the agent
+	if err := appCtx.InstanceRepository.CreateInstance(&instance, userEmail); err != nil {
+		if errors.Is(err, instance_repositories.ErrInstanceNameTaken) {
+			utils.HandleError(c, http.StatusConflict, "Instance name already in use", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create instance", err, userEmail)
+		return
+	}
+
+	secret, err := appCtx.InstanceCredentialsRepository.IssueInstanceCredential(instance.ID)
 	if err != nil {
-		utils.HandleError(c, http.StatusBadRequest, "Failed to create instance", err, userEmail)
+		appCtx.InstanceRepository.PurgeInstance(instance.ID)
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to issue instance credential", err, userEmail)
 		return
 	}
 
-	instance := instance_models.Instance{
-		PlanID:   plan.ID,
-		UserID:   user.ID,
-		RealID:   ec2Instance.Id,
-		Ready:    false,
-		Name:     "",
-		PublicIP: "",
+	ec2Instance, err := appCtx.InstanceClient.CreateInstance(c, &instanceType, instance_lifecycle.LaunchOptions(&instance, secret, int32(plan.Disk)))
+	if err != nil {
+		appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID)
+		appCtx.InstanceRepository.PurgeInstance(instance.ID)
+		utils.HandleError(c, http.StatusBadRequest, "Failed to create instance", err, userEmail)
+		return
 	}
 
-	if err := appCtx.InstanceRepository.CreateInstance(&instance); err != nil {
+	instance.RealID = ec2Instance.Id
+	if err := appCtx.InstanceRepository.SaveInstance(&instance); err != nil {
 		appCtx.InstanceClient.TerminateInstances(c, []string{ec2Instance.Id})
+		appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID)
+		appCtx.InstanceRepository.PurgeInstance(instance.ID)
 		utils.HandleError(c, http.StatusInternalServerError, "Failed to create instance", err, userEmail)
 		return
 	}
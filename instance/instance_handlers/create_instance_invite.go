@@ -0,0 +1,66 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for inviting a user to an instance
+type CreateInstanceInviteRequestBody struct {
+	Email string                       `json:"email" binding:"required,email"`
+	Role  instance_models.InstanceRole `json:"role" binding:"required,oneof=operator viewer"`
+}
+
+// CreateInstanceInvite invites an email to the owner's instance and returns the invite link.
+// The link is shown only once, the owner shares it with the invited user.
+func CreateInstanceInvite(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request CreateInstanceInviteRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	invite := instance_models.InstanceInvite{
+		InstanceID: instance.ID,
+		Email:      request.Email,
+		Role:       request.Role,
+		InvitedBy:  userEmail,
+		ExpiresAt:  time.Now().Add(utils.GetDurationEnv("INVITE_TTL", 7*24*time.Hour)),
+	}
+
+	token, err := appCtx.InstanceMemberRepository.CreateInstanceInvite(&invite)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create invite", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"invite": invite,
+		"token":  token,
+		"link":   strings.TrimSuffix(os.Getenv("INVITE_URL"), "/") + "/" + token,
+	})
+}
@@ -0,0 +1,46 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"gorm.io/gorm"
+)
+
+// GetInstanceActivity returns the latest heartbeat of the user's instance and its idle policy.
+// The activity is null until the agent reported since the last start.
+func GetInstanceActivity(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	activity, err := appCtx.InstanceRepository.GetInstanceActivity(instance.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		activity = nil
+	} else if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get activity", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activity":        activity,
+		"idleStopMinutes": instance.IdleStopMinutes,
+	})
+}
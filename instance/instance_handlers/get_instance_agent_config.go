@@ -0,0 +1,47 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstanceAgentConfig returns the service configuration and env values to the instance agent.
+// The agent calls it when told to reload after the user changed the env.
+func GetInstanceAgentConfig(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, instanceIDStr)
+		return
+	}
+
+	// check if instance exists
+	instance, err := appCtx.InstanceRepository.GetInstance(uint(instanceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, instanceIDStr)
+		return
+	}
+
+	// get service config
+	preset, err := appCtx.ServiceRepository.GetInstancePreset(instance.ServiceID, instance.ServicePresetID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, instanceIDStr)
+		return
+	}
+
+	// get env values chosen for the service
+	env, err := appCtx.InstanceRepository.GetInstanceEnv(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service env", err, instanceIDStr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"serviceConfig": preset.Config,
+		"env":           env,
+	})
+}
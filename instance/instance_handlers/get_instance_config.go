@@ -0,0 +1,49 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstanceConfig returns the env values of the user's instance along with the
+// preset env definitions they are validated against.
+func GetInstanceConfig(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	preset, err := appCtx.ServiceRepository.GetInstancePreset(instance.ServiceID, instance.ServicePresetID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, userEmail)
+		return
+	}
+
+	env, err := appCtx.InstanceRepository.GetInstanceEnv(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service env", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"env":           env,
+		"envDefinition": preset.Config.Env,
+		"presetVersion": preset.Version,
+	})
+}
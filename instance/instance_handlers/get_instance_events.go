@@ -0,0 +1,95 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// Comment lines sent while idle so proxies keep the connection open
+const eventsKeepAliveInterval = 30 * time.Second
+
+// GetInstanceEvents streams the changes of the user's instances as server-sent events.
+//
+// Clients reconnecting with the Last-Event-ID header (or the lastEventId query parameter)
+// receive the events they missed. When those are no longer available only a "reset" event is
+// sent and the client should reload its instances.
+func GetInstanceEvents(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get user", err, userEmail)
+		return
+	}
+
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("lastEventId")
+	}
+
+	var lastEventID uint64
+	if lastEventIDStr != "" {
+		if lastEventID, err = strconv.ParseUint(lastEventIDStr, 10, 64); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid last event id", err, userEmail)
+			return
+		}
+	}
+
+	missed, resetID, events, cancel := appCtx.Events.Subscribe(user.ID, lastEventID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if resetID != 0 {
+		c.Render(-1, sse.Event{
+			Id:    strconv.FormatUint(resetID, 10),
+			Event: "reset",
+			Data:  gin.H{},
+		})
+	}
+	for _, event := range missed {
+		renderInstanceEvent(c, event)
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-events:
+			// Closed when the client fell behind, it reconnects with its last event id
+			if !open {
+				return
+			}
+			renderInstanceEvent(c, event)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func renderInstanceEvent(c *gin.Context, event instance_events.Event) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(event.ID, 10),
+		Event: string(event.Type),
+		Data:  event,
+	})
+}
@@ -0,0 +1,38 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstanceInvites returns the pending invites of the owner's instance
+func GetInstanceInvites(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	invites, err := appCtx.InstanceMemberRepository.GetPendingInstanceInvites(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get invites", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
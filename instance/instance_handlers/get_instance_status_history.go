@@ -0,0 +1,45 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstanceStatusHistory returns a page of the status transitions of the user's instance, newest first.
+func GetInstanceStatusHistory(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	page, pageSize := utils.GetPagination(c)
+
+	changes, total, err := appCtx.InstanceRepository.GetInstanceStatusHistory(instance.ID, page, pageSize)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get status history", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"changes":  changes,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
@@ -0,0 +1,89 @@
+package instance_handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_details"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstances returns a page of the user's instances.
+//
+// Query parameters: name (substring), tag (repeatable, all must match), serviceId, planId,
+// status and sort (name, createdAt, status, planId or serviceId, prefixed with "-" for descending).
+// With live=true each instance includes its provider state, uptime and unbilled cycles.
+func GetInstances(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get user", err, userEmail)
+		return
+	}
+
+	filter := instance_repositories.InstanceFilter{
+		Name:   c.Query("name"),
+		Tags:   c.QueryArray("tag"),
+		Status: instance_models.InstanceStatus(c.Query("status")),
+		Sort:   c.Query("sort"),
+	}
+
+	if value := c.Query("serviceId"); value != "" {
+		serviceID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid service id", err, userEmail)
+			return
+		}
+		filter.ServiceID = uint(serviceID)
+	}
+
+	if value := c.Query("planId"); value != "" {
+		planID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid plan id", err, userEmail)
+			return
+		}
+		filter.PlanID = uint(planID)
+	}
+
+	// Tags are stored normalized
+	if len(filter.Tags) > 0 {
+		if filter.Tags, err = normalizeInstanceTags(filter.Tags); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid tags", err, userEmail)
+			return
+		}
+	}
+
+	page, pageSize := utils.GetPagination(c)
+
+	instances, total, err := appCtx.InstanceRepository.FindUserInstances(user.ID, filter, page, pageSize)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get instances", err, userEmail)
+		return
+	}
+
+	response := gin.H{
+		"instances": instances,
+		"total":     total,
+		"page":      page,
+		"pageSize":  pageSize,
+	}
+
+	// Live provider state is opt-in, a provider failure still returns the records
+	if c.Query("live") == "true" {
+		details, err := instance_details.Enrich(c, appCtx, *instances)
+		if err != nil {
+			log.Printf("%s: Failed to get live instance details: %v", userEmail, err)
+			response["liveError"] = "Live instance details are unavailable"
+		}
+		response["instances"] = details
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,28 @@
+package instance_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetSharedInstances returns the instances of other users the user is a member of, with the user's role
+func GetSharedInstances(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get user", err, userEmail)
+		return
+	}
+
+	instances, err := appCtx.InstanceMemberRepository.GetSharedInstances(user.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get instances", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
@@ -0,0 +1,54 @@
+package instance_handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxInstanceNameLength        = 64
+	maxInstanceDescriptionLength = 500
+	maxInstanceTags              = 20
+	maxInstanceTagLength         = 32
+)
+
+// normalizeInstanceName trims the name and checks its length
+func normalizeInstanceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxInstanceNameLength {
+		return "", fmt.Errorf("name is longer than %d characters", maxInstanceNameLength)
+	}
+	return name, nil
+}
+
+func validateInstanceDescription(description string) error {
+	if utf8.RuneCountInString(description) > maxInstanceDescriptionLength {
+		return fmt.Errorf("description is longer than %d characters", maxInstanceDescriptionLength)
+	}
+	return nil
+}
+
+// normalizeInstanceTags trims, lowercases and deduplicates the tags
+func normalizeInstanceTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, errors.New("tags cannot be empty")
+		}
+		if utf8.RuneCountInString(tag) > maxInstanceTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxInstanceTagLength)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > maxInstanceTags {
+		return nil, fmt.Errorf("an instance can have at most %d tags", maxInstanceTags)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,141 @@
+package instance_handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// containerStateRunning is the container state of a healthy service
+const containerStateRunning = "running"
+
+type HeartbeatPayload struct {
+	PlayerCount    int     `json:"playerCount" binding:"min=0"`
+	CPUPercent     float64 `json:"cpuPercent" binding:"min=0"`
+	MemoryUsedMiB  int     `json:"memoryUsedMiB" binding:"min=0"`
+	DiskUsedMiB    int     `json:"diskUsedMiB" binding:"min=0"`
+	AgentVersion   string  `json:"agentVersion"`
+	ContainerState string  `json:"containerState"` // Agents predating container states leave it empty
+}
+
+// OnInstanceHeartbeat records the activity reported by the agent, updates the health of the
+// instance and stops it once it has been without players for longer than its idle policy allows.
+func OnInstanceHeartbeat(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, instanceIDStr)
+		return
+	}
+
+	var request HeartbeatPayload
+	if err := c.BindJSON(&request); err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid request", err, instanceIDStr)
+		return
+	}
+
+	// check if instance exists
+	instance, err := appCtx.InstanceRepository.GetInstance(uint(instanceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, instanceIDStr)
+		return
+	}
+
+	now := time.Now()
+	activity := instance_models.InstanceActivity{
+		InstanceID:     instance.ID,
+		ReportedAt:     now,
+		PlayerCount:    request.PlayerCount,
+		CPUPercent:     request.CPUPercent,
+		MemoryUsedMiB:  request.MemoryUsedMiB,
+		DiskUsedMiB:    request.DiskUsedMiB,
+		AgentVersion:   request.AgentVersion,
+		ContainerState: request.ContainerState,
+	}
+	if err := appCtx.InstanceRepository.RecordInstanceActivity(&activity); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to record heartbeat", err, instanceIDStr)
+		return
+	}
+	if err := appCtx.InstanceRepository.RecordInstanceHeartbeat(instance, now); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to record heartbeat", err, instanceIDStr)
+		return
+	}
+
+	if instance.Status == instance_models.InstanceStatusRunning {
+		if err := updateInstanceHealth(appCtx, instance, request.ContainerState); err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to update instance health", err, instanceIDStr)
+			return
+		}
+	}
+
+	stopping, err := enforceIdlePolicy(c, appCtx, instance, &activity, now)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to stop idle instance", err, instanceIDStr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"health":          instance.Health,
+		"idleStopMinutes": instance.IdleStopMinutes,
+		"idleSince":       activity.IdleSince,
+		"stopping":        stopping,
+	})
+}
+
+// updateInstanceHealth marks a running instance healthy unless its service container is down
+func updateInstanceHealth(appCtx *app.Context, instance *instance_models.Instance, containerState string) error {
+	if containerState == "" || containerState == containerStateRunning {
+		_, err := appCtx.InstanceRepository.SetInstanceHealth(instance, instance_models.InstanceHealthHealthy, "heartbeat received")
+		return err
+	}
+
+	reason := "service container is " + containerState
+	changed, err := appCtx.InstanceRepository.SetInstanceHealth(instance, instance_models.InstanceHealthUnhealthy, reason)
+	if err != nil || !changed {
+		return err
+	}
+
+	message := fmt.Sprintf("Instance %s is unhealthy: %s", notification_delivery.InstanceLabel(instance), reason)
+	if err := notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindUnhealthy, message); err != nil {
+		log.Printf("%d: Failed to notify unhealthy instance: %v", instance.ID, err)
+	}
+	return nil
+}
+
+// enforceIdlePolicy stops a running instance that has been idle for too long and tells the owner
+func enforceIdlePolicy(c *gin.Context, appCtx *app.Context, instance *instance_models.Instance, activity *instance_models.InstanceActivity, now time.Time) (bool, error) {
+	if instance.IdleStopMinutes <= 0 || activity.IdleSince == nil || instance.Status != instance_models.InstanceStatusRunning {
+		return false, nil
+	}
+	if now.Sub(*activity.IdleSince) < time.Duration(instance.IdleStopMinutes)*time.Minute {
+		return false, nil
+	}
+
+	reason := fmt.Sprintf("no players for %d minutes", instance.IdleStopMinutes)
+	if err := instance_lifecycle.StopInstance(c, appCtx, instance, "idle-policy", reason); err != nil {
+		// Already being stopped by someone else
+		if errors.Is(err, instance_repositories.ErrIllegalTransition) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	message := fmt.Sprintf("Instance %s had no players for %d minutes and is being stopped", notification_delivery.InstanceLabel(instance), instance.IdleStopMinutes)
+	if err := notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindIdleStopped, message); err != nil {
+		log.Printf("%d: Failed to notify idle stop: %v", instance.ID, err)
+	}
+
+	return true, nil
+}
@@ -1,11 +1,15 @@
 package instance_handlers
 
 import (
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
@@ -35,26 +39,48 @@ func OnInstanceShutdown(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
-	// update instance
-	instance.Ready = false
-	instance.PublicIP = ""
-	if err := appCtx.InstanceRepository.SaveInstance(instance); err != nil {
-		utils.HandleError(c, http.StatusInternalServerError, "Failed to save instance", err, instanceIDStr)
+	// bill the running time up to now, the agent's figure is only kept for auditing
+	log.Printf("%s: Agent reported %d burned cycles", instanceIDStr, request.BurnedCycleAmount)
+
+	var meterResult *instance_billing.MeterResult
+	if instance.MeteredAt != nil {
+		meterResult, err = instance_billing.MeterInstance(appCtx, instance, time.Now())
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to meter instance", err, instanceIDStr)
+			return
+		}
+	}
+
+	// an archiving, pending deletion or terminating instance shuts down too, it keeps its status
+	switch instance.Status {
+	case instance_models.InstanceStatusStopped, instance_models.InstanceStatusArchiving, instance_models.InstanceStatusPendingDeletion, instance_models.InstanceStatusTerminating, instance_models.InstanceStatusTerminated:
+	default:
+		if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, "instance-agent", "agent shutdown"); err != nil {
+			handleLifecycleError(c, http.StatusInternalServerError, "Failed to update instance status", err, instanceIDStr)
+			return
+		}
+	}
+
+	// the reported activity only describes the ended run
+	if err := appCtx.InstanceRepository.ResetInstanceActivity(instance.ID); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to reset activity", err, instanceIDStr)
 		return
 	}
 
-	// create burned cycle
-	burnedCycle := instance_models.InstanceBurnedCycle{
-		InstanceID: instance.ID,
-		Amount:     request.BurnedCycleAmount,
+	// update instance, billing ends even if a metering pass moved the start meanwhile
+	if err := appCtx.InstanceRepository.ClearInstanceMetering(instance); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to stop metering", err, instanceIDStr)
+		return
 	}
-	if err = appCtx.InstanceBurnedCyclesRepository.CreateBurnedInstanceBurnedCycles(&burnedCycle); err != nil {
-		utils.HandleError(c, http.StatusInternalServerError, "Failed to create burned cycle", err, instanceIDStr)
+	instance.PublicIP = ""
+	if err := appCtx.InstanceRepository.SaveInstance(instance); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save instance", err, instanceIDStr)
 		return
 	}
+	appCtx.Events.Publish(instance.UserID, instance.ID, instance_events.EventIP, instance_events.IPData{})
 
-	// return burned cycles
+	// return metering result
 	c.JSON(http.StatusOK, gin.H{
-		"burnedCycle": burnedCycle,
+		"meter": meterResult,
 	})
 }
@@ -3,11 +3,15 @@ package instance_handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
-	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
 
@@ -37,13 +41,48 @@ func OnInstanceStartup(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
-	// update instance
-	instance.Ready = true
+	// the service is up
+	if instance.Status != instance_models.InstanceStatusRunning {
+		if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusRunning, "instance-agent", "agent startup"); err != nil {
+			handleLifecycleError(c, http.StatusInternalServerError, "Failed to update instance status", err, instanceIDStr)
+			return
+		}
+	}
+
+	// the idle time of the new run starts with its first heartbeat
+	if err := appCtx.InstanceRepository.ResetInstanceActivity(instance.ID); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to reset activity", err, instanceIDStr)
+		return
+	}
+
+	// the startup counts as the first heartbeat
+	if err := appCtx.InstanceRepository.RecordInstanceHeartbeat(instance, time.Now()); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to record heartbeat", err, instanceIDStr)
+		return
+	}
+	if _, err := appCtx.InstanceRepository.SetInstanceHealth(instance, instance_models.InstanceHealthHealthy, "agent startup"); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update instance health", err, instanceIDStr)
+		return
+	}
+
+	// update instance, billing starts now
 	instance.PublicIP = request.PublicIP
 	if err := appCtx.InstanceRepository.SaveInstance(instance); err != nil {
 		utils.HandleError(c, http.StatusInternalServerError, "Failed to save instance", err, instanceIDStr)
 		return
 	}
+	if instance.MeteredAt == nil {
+		now := time.Now()
+		started, err := appCtx.InstanceRepository.UpdateInstanceMeteredAt(instance, &now)
+		if err == nil && started {
+			err = appCtx.InstanceRepository.SetCycleWarningMinutes(instance, 0)
+		}
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to start metering", err, instanceIDStr)
+			return
+		}
+	}
+	appCtx.Events.Publish(instance.UserID, instance.ID, instance_events.EventIP, instance_events.IPData{PublicIP: instance.PublicIP})
 
 	// get plan
 	plan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
@@ -53,34 +92,55 @@ func OnInstanceStartup(c *gin.Context, appCtx *app.Context) {
 	}
 
 	// get available cycles for this instance
-	cyclesAmount, err := appCtx.InstanceCyclesRepository.GetInstanceCyclesSum(instance.ID)
+	cyclesAmount, err := appCtx.LedgerRepository.GetBalance(instance_billing.FundingAccount(instance))
 	if err != nil {
 		utils.HandleError(c, http.StatusInternalServerError, "Failed to get cycles amount", err, instanceIDStr)
 		return
 	}
 
-	// get services
-	services, err := appCtx.ServiceRepository.GetServices()
+	// get service
+	service, err := appCtx.ServiceRepository.GetService(instance.ServiceID)
 	if err != nil {
-		utils.HandleError(c, http.StatusInternalServerError, "Cannot get services", err, "null")
+		utils.HandleError(c, http.StatusInternalServerError, "Service not found", err, instanceIDStr)
 		return
 	}
 
-	// get service configs
-	configs, err := service_presets.GetServiceConfigurations()
+	// get service config
+	preset, err := appCtx.ServiceRepository.GetInstancePreset(service.ID, instance.ServicePresetID)
 	if err != nil {
-		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, "null")
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, instanceIDStr)
+		return
+	}
+	config := preset.Config
+
+	// get env values chosen for the service
+	env, err := appCtx.InstanceRepository.GetInstanceEnv(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service env", err, instanceIDStr)
 		return
 	}
 
 	if request.FailedBurnedCycleAmount > 0 {
-		// create burned cycle
-		burnedCycle := &instance_models.InstanceBurnedCycle{
-			InstanceID: instance.ID,
-			Amount:     request.FailedBurnedCycleAmount,
+		// burn the cycles used by the failed startup
+		_, err = appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+			From:           instance_billing.FundingAccount(instance),
+			To:             ledger_repositories.SystemAccount(ledger_models.SystemAccountBurned),
+			Amount:         request.FailedBurnedCycleAmount,
+			Reason:         ledger_models.ReasonFailedStartupBurn,
+			Actor:          "instance-agent",
+			UserID:         instance.UserID,
+			InstanceID:     instance.ID,
+			ClampToBalance: true,
+		})
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to burn cycles", err, instanceIDStr)
+			return
 		}
-		if err = appCtx.InstanceBurnedCyclesRepository.CreateBurnedInstanceBurnedCycles(burnedCycle); err != nil {
-			utils.HandleError(c, http.StatusInternalServerError, "Failed to create burned cycle", err, instanceIDStr)
+
+		// the balance returned to the agent must reflect the burn
+		cyclesAmount, err = appCtx.LedgerRepository.GetBalance(instance_billing.FundingAccount(instance))
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to get cycles amount", err, instanceIDStr)
 			return
 		}
 	}
@@ -90,7 +150,8 @@ func OnInstanceStartup(c *gin.Context, appCtx *app.Context) {
 		"instanceMemory": plan.Memory,
 		"ownerId":        instance.UserID,
 		"cycles":         cyclesAmount,
-		"services":       services,
-		"serviceConfigs": configs,
+		"service":        service,
+		"serviceConfig":  config,
+		"env":            env,
 	})
 }
@@ -0,0 +1,29 @@
+package instance_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_jobs"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetReconcileReport returns the latest report produced by the reconciler.
+func GetReconcileReport(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	report, err := appCtx.ReconcileReportRepository.GetLatestReconcileReport()
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "No reconcile report yet", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ReconcileInstances runs the reconciler immediately and returns its report.
+func ReconcileInstances(c *gin.Context, appCtx *app.Context) {
+	report := instance_jobs.Reconcile(c, appCtx)
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,59 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// RemoveInstanceMember revokes the access of a member. The owner removes anyone, members only remove themselves.
+func RemoveInstanceMember(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	memberID64, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get user", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, role, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if uint(memberID64) == instance.UserID {
+		utils.HandleError(c, http.StatusBadRequest, "The owner cannot be removed", errors.New("owner cannot be removed"), userEmail)
+		return
+	}
+	if role != instance_models.InstanceRoleOwner && uint(memberID64) != user.ID {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", errors.New("only the owner removes other members"), userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceMemberRepository.RemoveInstanceMember(instance.ID, uint(memberID64)); err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Member not found", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
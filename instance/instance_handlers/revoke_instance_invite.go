@@ -0,0 +1,43 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// RevokeInstanceInvite revokes a pending invite of the owner's instance
+func RevokeInstanceInvite(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	inviteID64, err := strconv.ParseUint(c.Param("inviteId"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid invite id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceMemberRepository.RevokeInstanceInvite(instance.ID, uint(inviteID64)); err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Invite not found", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
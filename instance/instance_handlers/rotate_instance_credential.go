@@ -0,0 +1,33 @@
+package instance_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// RotateInstanceCredential issues a new credential for the instance agent and returns its secret once.
+// The agent writes the secret to /etc/gshub/agent.env itself before using it, the secret never passes
+// through the provider. The credential the agent called with stays valid until the new one is first
+// used, so an agent that could not save the new secret keeps working with the old one.
+func RotateInstanceCredential(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, instanceIDStr)
+		return
+	}
+
+	secret, err := appCtx.InstanceCredentialsRepository.RotateInstanceCredential(uint(instanceID64), c.GetUint("instanceCredentialID"))
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to rotate instance credential", err, instanceIDStr)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+	})
+}
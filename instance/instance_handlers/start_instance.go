@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
 
@@ -21,16 +23,16 @@ func StartInstance(c *gin.Context, appCtx *app.Context) {
 	}
 
 	// Check if the instance exists
-	instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
 	if err != nil {
-		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		handleInstanceAccessError(c, err, userEmail)
 		return
 	}
 
 	// Start the instance
-	err = appCtx.InstanceClient.StartInstances(c, []string{instance.RealID})
+	err = instance_lifecycle.StartInstance(c, appCtx, instance, userEmail, "started by user")
 	if err != nil {
-		utils.HandleError(c, http.StatusBadRequest, "Unable to start instance", err, userEmail)
+		handleLifecycleError(c, http.StatusBadRequest, "Unable to start instance", err, userEmail)
 		return
 	}
 
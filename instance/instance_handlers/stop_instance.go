@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
 
@@ -21,16 +23,16 @@ func StopInstance(c *gin.Context, appCtx *app.Context) {
 	}
 
 	// Check if the instance exists
-	server, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
+	server, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
 	if err != nil {
-		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		handleInstanceAccessError(c, err, userEmail)
 		return
 	}
 
 	// Stop the instance
-	err = appCtx.InstanceClient.StopInstances(c, []string{server.RealID})
+	err = instance_lifecycle.StopInstance(c, appCtx, server, userEmail, "stopped by user")
 	if err != nil {
-		utils.HandleError(c, http.StatusBadRequest, "Unable to stop instance", err, userEmail)
+		handleLifecycleError(c, http.StatusBadRequest, "Unable to stop instance", err, userEmail)
 		return
 	}
 
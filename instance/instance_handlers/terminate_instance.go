@@ -1,19 +1,28 @@
 package instance_handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
 	"github.com/mooncorn/gshub-main-api/utils"
 )
 
-// TerminateInstance terminates an EC2 instance and deletes its record from the instance repository.
+// The payload for terminating an instance
+type TerminateInstanceRequestBody struct {
+	FinalBackup bool `json:"finalBackup"` // Back up the disk right before the instance is terminated
+}
+
+// TerminateInstance schedules the deletion of the user's instance.
 //
-// If the instance exists, it proceeds to terminate the instance using the InstanceClient.
-// After successfully terminating the instance, it deletes the corresponding instance record
-// from the instance repository. If any step fails, an appropriate error response is returned.
+// The instance is stopped and becomes pending deletion for INSTANCE_DELETION_GRACE, during which
+// the user can cancel. Afterwards the deletion job takes the final backup if requested, terminates
+// the EC2 instance and cleans up its cycles, schedules and backups.
 func TerminateInstance(c *gin.Context, appCtx *app.Context) {
 	instanceIDStr := c.Param("id")
 	userEmail := c.GetString("userEmail")
@@ -24,6 +33,13 @@ func TerminateInstance(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
+	// The body is optional
+	var request TerminateInstanceRequestBody
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
 	// Check if the instance exists
 	instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
 	if err != nil {
@@ -31,15 +47,38 @@ func TerminateInstance(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
-	// Terminate the instance
-	if err := appCtx.InstanceClient.TerminateInstances(c, []string{instance.RealID}); err != nil {
-		utils.HandleError(c, http.StatusBadRequest, "Unable to terminate instance", err, userEmail)
+	grace := utils.GetDurationEnv("INSTANCE_DELETION_GRACE", 24*time.Hour)
+	if err := instance_lifecycle.ScheduleTermination(c, appCtx, instance, grace, request.FinalBackup, userEmail); err != nil {
+		handleLifecycleError(c, http.StatusBadRequest, "Unable to terminate instance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"deletionScheduledAt": instance.DeletionScheduledAt,
+		"finalBackup":         instance.FinalBackup,
+	})
+}
+
+// CancelInstanceTermination cancels the pending deletion of the user's instance, which is left stopped.
+func CancelInstanceTermination(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
 		return
 	}
 
-	// Delete the instance record
-	if err := appCtx.InstanceRepository.DeleteUserInstance(userEmail, instance.ID); err != nil {
-		utils.HandleError(c, http.StatusBadRequest, "Unable to delete instance", err, userEmail)
+	if err := appCtx.InstanceRepository.CancelInstanceDeletion(instance, userEmail); err != nil {
+		handleLifecycleError(c, http.StatusInternalServerError, "Unable to cancel termination", err, userEmail)
 		return
 	}
 
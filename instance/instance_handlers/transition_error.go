@@ -0,0 +1,20 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// handleLifecycleError responds with 409 Conflict for illegal status transitions
+// and with the given status and message for any other error.
+func handleLifecycleError(c *gin.Context, status int, message string, err error, userEmail string) {
+	if errors.Is(err, instance_repositories.ErrIllegalTransition) {
+		utils.HandleError(c, http.StatusConflict, "Instance cannot do this in its current status", err, userEmail)
+		return
+	}
+	utils.HandleError(c, status, message, err, userEmail)
+}
@@ -0,0 +1,85 @@
+package instance_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for updating the details of an instance, fields left out are not changed
+type UpdateInstanceRequestBody struct {
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+}
+
+// UpdateInstance renames the user's instance and changes its description and tags.
+func UpdateInstance(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request UpdateInstanceRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, uint(instanceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	name, description := instance.Name, instance.Description
+	if request.Name != nil {
+		if name, err = normalizeInstanceName(*request.Name); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid name", err, userEmail)
+			return
+		}
+	}
+	if request.Description != nil {
+		if err := validateInstanceDescription(*request.Description); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid description", err, userEmail)
+			return
+		}
+		description = *request.Description
+	}
+
+	var tags []string
+	if request.Tags != nil {
+		if tags, err = normalizeInstanceTags(*request.Tags); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid tags", err, userEmail)
+			return
+		}
+	}
+
+	if err := appCtx.InstanceRepository.UpdateInstanceDetails(instance, name, description); err != nil {
+		if errors.Is(err, instance_repositories.ErrInstanceNameTaken) {
+			utils.HandleError(c, http.StatusConflict, "Instance name already in use", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update instance", err, userEmail)
+		return
+	}
+
+	if request.Tags != nil {
+		if err := appCtx.InstanceRepository.SetInstanceTags(instance, tags); err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to update tags", err, userEmail)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
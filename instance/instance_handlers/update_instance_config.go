@@ -0,0 +1,80 @@
+package instance_handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// Asks the instance agent to fetch its configuration from GET /config/:id again
+const reloadConfigCommand = "curl -fsS -X POST http://localhost:3001/config/reload"
+
+// The payload for updating the env of an instance. Keys that are left out fall back to
+// the preset defaults.
+type UpdateInstanceConfigRequestBody struct {
+	Env map[string]string `json:"env"`
+}
+
+// UpdateInstanceConfig validates the env values against the instance's preset and replaces them.
+// A running instance is told to reload them, otherwise they are picked up on the next start.
+func UpdateInstanceConfig(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request UpdateInstanceConfigRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	preset, err := appCtx.ServiceRepository.GetInstancePreset(instance.ServiceID, instance.ServicePresetID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, userEmail)
+		return
+	}
+
+	env, err := preset.Config.ResolveEnv(request.Env)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service env", err, userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceRepository.ReplaceInstanceEnv(instance.ID, env); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save service env", err, userEmail)
+		return
+	}
+
+	// The values are saved either way, a failed push only delays them until the next start
+	pushed := false
+	if instance.Status == instance_models.InstanceStatusRunning {
+		command := reloadConfigCommand
+		if err := appCtx.InstanceClient.SendCommand(c, &command, &[]string{instance.RealID}); err != nil {
+			log.Printf("%s: Failed to push config: %v", instanceIDStr, err)
+		} else {
+			pushed = true
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"env":    env,
+		"pushed": pushed,
+	})
+}
@@ -0,0 +1,99 @@
+package instance_handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for choosing what an instance burns cycles from, a null wallet means its own balance
+type UpdateInstanceFundingRequestBody struct {
+	WalletID *uint `json:"walletId"`
+}
+
+// UpdateInstanceFunding makes the owner's instance burn cycles from one of their wallets or from its own
+// balance. Cycles left on the instance's own balance are contributed to the wallet by the owner.
+func UpdateInstanceFunding(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request UpdateInstanceFundingRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOwner)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if request.WalletID != nil {
+		member, err := appCtx.WalletRepository.IsWalletMember(*request.WalletID, instance.UserID)
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to get wallet", err, userEmail)
+			return
+		}
+		if !member {
+			utils.HandleError(c, http.StatusNotFound, "Wallet not found", errors.New("not a wallet member"), userEmail)
+			return
+		}
+	}
+
+	if err := appCtx.InstanceRepository.SetInstanceWallet(instance, request.WalletID); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update funding", err, userEmail)
+		return
+	}
+
+	if request.WalletID != nil {
+		moveInstanceBalanceToWallet(appCtx, instance, userEmail)
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
+
+// moveInstanceBalanceToWallet contributes the cycles left on the instance's own balance to its wallet.
+// On failure they stay on the instance and are burned again once it is funded by itself.
+func moveInstanceBalanceToWallet(appCtx *app.Context, instance *instance_models.Instance, userEmail string) {
+	account := ledger_repositories.InstanceAccount(instance.ID)
+
+	balance, err := appCtx.LedgerRepository.GetBalance(account)
+	if err != nil || balance <= 0 {
+		if err != nil {
+			log.Printf("%s: Failed to get balance of instance %d: %v", userEmail, instance.ID, err)
+		}
+		return
+	}
+
+	_, err = appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+		From:           account,
+		To:             ledger_repositories.WalletAccount(*instance.WalletID),
+		Amount:         uint(balance),
+		Reason:         ledger_models.ReasonContribution,
+		Description:    fmt.Sprintf("Balance of instance #%d", instance.ID),
+		Actor:          userEmail,
+		UserID:         instance.UserID,
+		InstanceID:     instance.ID,
+		ClampToBalance: true,
+	})
+	if err != nil {
+		log.Printf("%s: Failed to move balance of instance %d to wallet: %v", userEmail, instance.ID, err)
+	}
+}
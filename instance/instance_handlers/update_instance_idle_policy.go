@@ -0,0 +1,61 @@
+package instance_handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+const (
+	minIdleStopMinutes = 5
+	maxIdleStopMinutes = 24 * 60
+)
+
+// The payload for changing the idle policy, 0 keeps the instance running without players
+type UpdateInstanceIdlePolicyRequestBody struct {
+	IdleStopMinutes *int `json:"idleStopMinutes" binding:"required"`
+}
+
+// UpdateInstanceIdlePolicy sets how long the user's instance may run without players
+func UpdateInstanceIdlePolicy(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request UpdateInstanceIdlePolicyRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	minutes := *request.IdleStopMinutes
+	if minutes != 0 && (minutes < minIdleStopMinutes || minutes > maxIdleStopMinutes) {
+		err := fmt.Errorf("idle stop minutes must be 0 or between %d and %d", minIdleStopMinutes, maxIdleStopMinutes)
+		utils.HandleError(c, http.StatusBadRequest, err.Error(), err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
+	if err != nil {
+		handleInstanceAccessError(c, err, userEmail)
+		return
+	}
+
+	if err := appCtx.InstanceRepository.SetIdleStopMinutes(instance, minutes); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update idle policy", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
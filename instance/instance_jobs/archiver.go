@@ -0,0 +1,116 @@
+package instance_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+	"gorm.io/gorm"
+)
+
+// RunArchiver moves instances in and out of cold storage every interval. It never returns.
+// It finishes archives whose snapshot completed, deletes the archive of instances running again
+// and, when archiveAfter is positive, archives instances stopped for longer than that.
+func RunArchiver(appCtx *app.Context, interval time.Duration, archiveAfter time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx := context.Background()
+
+		if err := finishArchives(ctx, appCtx); err != nil {
+			log.Printf("archiver: Error: failed to finish archives: %v", err)
+		}
+
+		if err := deleteUnarchivedBackups(ctx, appCtx); err != nil {
+			log.Printf("archiver: Error: failed to delete archives: %v", err)
+		}
+
+		if archiveAfter > 0 {
+			if err := archiveStoppedInstances(ctx, appCtx, archiveAfter); err != nil {
+				log.Printf("archiver: Error: failed to archive stopped instances: %v", err)
+			}
+		}
+	}
+}
+
+func finishArchives(ctx context.Context, appCtx *app.Context) error {
+	instances, err := appCtx.InstanceRepository.GetInstancesByStatus(instance_models.InstanceStatusArchiving)
+	if err != nil {
+		return err
+	}
+
+	for i := range *instances {
+		instance := &(*instances)[i]
+		archived, err := instance_lifecycle.FinishArchive(ctx, appCtx, instance)
+		if err != nil {
+			log.Printf("archiver: Error: instance %d: %v", instance.ID, err)
+			continue
+		}
+		if !archived {
+			continue
+		}
+
+		message := fmt.Sprintf("Instance %s was archived, only its disk snapshot is kept. Unarchive it to use it again.", notification_delivery.InstanceLabel(instance))
+		if err := notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindArchived, message); err != nil {
+			log.Printf("archiver: Error: instance %d: failed to notify: %v", instance.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// deleteUnarchivedBackups deletes the archive of instances that run again from it
+func deleteUnarchivedBackups(ctx context.Context, appCtx *app.Context) error {
+	instances, err := appCtx.InstanceRepository.GetUnarchivedInstances()
+	if err != nil {
+		return err
+	}
+
+	for i := range *instances {
+		instance := &(*instances)[i]
+
+		// Already gone when the owner deleted it
+		backup, err := appCtx.BackupRepository.GetBackup(*instance.ArchiveBackupID)
+		if err == nil {
+			err = backup_lifecycle.DeleteBackup(ctx, appCtx, backup)
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+		if err != nil {
+			log.Printf("archiver: Error: instance %d: failed to delete archive: %v", instance.ID, err)
+			continue
+		}
+
+		if err := appCtx.InstanceRepository.SetInstanceArchive(instance, nil); err != nil {
+			log.Printf("archiver: Error: instance %d: %v", instance.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func archiveStoppedInstances(ctx context.Context, appCtx *app.Context, archiveAfter time.Duration) error {
+	instances, err := appCtx.InstanceRepository.GetInstancesStoppedSince(time.Now().Add(-archiveAfter))
+	if err != nil {
+		return err
+	}
+
+	for i := range *instances {
+		instance := &(*instances)[i]
+		reason := fmt.Sprintf("stopped for more than %s", archiveAfter)
+		if err := instance_lifecycle.ArchiveInstance(ctx, appCtx, instance, "archiver", reason); err != nil {
+			log.Printf("archiver: Error: instance %d: %v", instance.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package instance_jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+)
+
+// RunDeletions terminates the instances whose deletion grace period ended every interval. It never returns.
+func RunDeletions(appCtx *app.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		instances, err := appCtx.InstanceRepository.GetInstancesDueForDeletion(time.Now())
+		if err != nil {
+			log.Printf("deletion: Error: failed to get instances: %v", err)
+			continue
+		}
+
+		for i := range *instances {
+			instance := &(*instances)[i]
+			if err := deleteInstance(context.Background(), appCtx, instance); err != nil {
+				log.Printf("deletion: Error: instance %d: %v", instance.ID, err)
+			}
+		}
+	}
+}
+
+// deleteInstance takes the final backup if requested, terminates the instance and cleans up
+// what belonged to it. The owner keeps the final backup and gets the remaining balance back.
+func deleteInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance) error {
+	// Without the backup the instance is kept and retried on the next pass.
+	// An archived instance has no disk left, its archive is the final backup.
+	var finalBackupID uint
+	switch {
+	case !instance.FinalBackup:
+	case instance.FinalBackupID != nil:
+		finalBackupID = *instance.FinalBackupID
+	case instance.RealID == "" && instance.ArchiveBackupID != nil:
+		finalBackupID = *instance.ArchiveBackupID
+	default:
+		backup, err := backup_lifecycle.TakeBackup(ctx, appCtx, instance, false, "final backup before termination", "deletion")
+		if err != nil {
+			return fmt.Errorf("failed to take final backup: %v", err)
+		}
+		// Retries reuse it instead of taking another one
+		if err := appCtx.InstanceRepository.SetInstanceFinalBackup(instance, backup.ID); err != nil {
+			return fmt.Errorf("failed to record final backup %d: %v", backup.ID, err)
+		}
+		finalBackupID = backup.ID
+	}
+
+	// A provider failure leaves the instance pending deletion and any later failure leaves it
+	// terminating or terminated, either way it is retried on the next pass
+	if err := instance_lifecycle.TerminateInstance(ctx, appCtx, instance, "deletion", "deletion grace period ended"); err != nil {
+		return fmt.Errorf("failed to terminate: %v", err)
+	}
+
+	// The record is gone, cleanup failures are only logged
+	if err := refundInstanceBalance(appCtx, instance); err != nil {
+		log.Printf("deletion: Error: instance %d: failed to refund balance: %v", instance.ID, err)
+	}
+
+	if err := appCtx.ScheduleRepository.DeleteInstanceSchedules(instance.ID); err != nil {
+		log.Printf("deletion: Error: instance %d: failed to delete schedules: %v", instance.ID, err)
+	}
+
+	if err := appCtx.BackupRepository.DeleteBackupPolicy(instance.ID); err != nil {
+		log.Printf("deletion: Error: instance %d: failed to delete backup policy: %v", instance.ID, err)
+	}
+
+	backups, err := appCtx.BackupRepository.GetInstanceBackups(instance.ID)
+	if err != nil {
+		log.Printf("deletion: Error: instance %d: failed to get backups: %v", instance.ID, err)
+	} else {
+		for i := range *backups {
+			backup := &(*backups)[i]
+
+			// Snapshots still being taken cannot be deleted, the owner can delete them later
+			if backup.ID == finalBackupID || backup.Status == backup_models.BackupStatusPending {
+				continue
+			}
+			if err := backup_lifecycle.DeleteBackup(ctx, appCtx, backup); err != nil {
+				log.Printf("deletion: Error: instance %d: failed to delete backup %d: %v", instance.ID, backup.ID, err)
+			}
+		}
+	}
+
+	message := fmt.Sprintf("Instance %s was terminated", notification_delivery.InstanceLabel(instance))
+	if finalBackupID != 0 {
+		message += fmt.Sprintf(", its final backup is %d", finalBackupID)
+	}
+	return notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindTerminated, message)
+}
+
+// refundInstanceBalance moves the cycles left on the instance to its owner
+func refundInstanceBalance(appCtx *app.Context, instance *instance_models.Instance) error {
+	account := ledger_repositories.InstanceAccount(instance.ID)
+	balance, err := appCtx.LedgerRepository.GetBalance(account)
+	if err != nil || balance <= 0 {
+		return err
+	}
+
+	_, err = appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+		From:           account,
+		To:             ledger_repositories.UserAccount(instance.UserID),
+		Amount:         uint(balance),
+		Reason:         ledger_models.ReasonTermination,
+		Description:    fmt.Sprintf("Balance of terminated instance %d", instance.ID),
+		Actor:          "deletion",
+		UserID:         instance.UserID,
+		InstanceID:     instance.ID,
+		IdempotencyKey: fmt.Sprintf("terminate:%d", instance.ID),
+	})
+	return err
+}
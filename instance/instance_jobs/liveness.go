@@ -0,0 +1,46 @@
+package instance_jobs
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+)
+
+// RunLivenessMonitor marks running instances unhealthy every interval once their agent
+// missed heartbeats for longer than timeout. It never returns.
+func RunLivenessMonitor(appCtx *app.Context, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		instances, err := appCtx.InstanceRepository.GetSilentInstances(time.Now().Add(-timeout))
+		if err != nil {
+			log.Printf("liveness: Error: failed to get instances: %v", err)
+			continue
+		}
+
+		for i := range *instances {
+			instance := &(*instances)[i]
+			if err := markUnresponsive(appCtx, instance, timeout); err != nil {
+				log.Printf("liveness: Error: instance %d: %v", instance.ID, err)
+			}
+		}
+	}
+}
+
+// markUnresponsive marks the instance unhealthy and tells the owner. The next heartbeat marks it healthy again.
+func markUnresponsive(appCtx *app.Context, instance *instance_models.Instance, timeout time.Duration) error {
+	reason := fmt.Sprintf("no heartbeat for %s", timeout)
+	changed, err := appCtx.InstanceRepository.SetInstanceHealth(instance, instance_models.InstanceHealthUnhealthy, reason)
+	if err != nil || !changed {
+		return err
+	}
+
+	return notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindUnhealthy,
+		fmt.Sprintf("Instance %s is unhealthy: %s", notification_delivery.InstanceLabel(instance), reason))
+}
@@ -0,0 +1,117 @@
+package instance_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/notification/notification_delivery"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+)
+
+const defaultWarningMinutes = "30,10"
+
+// RunMetering bills running instances every interval. It never returns.
+func RunMetering(appCtx *app.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		instances, err := appCtx.InstanceRepository.GetMeteredInstances()
+		if err != nil {
+			log.Printf("metering: Error: failed to get instances: %v", err)
+			continue
+		}
+
+		for i := range *instances {
+			instance := &(*instances)[i]
+			if err := enforceBalance(context.Background(), appCtx, instance); err != nil {
+				log.Printf("metering: Error: instance %d: %v", instance.ID, err)
+			}
+		}
+	}
+}
+
+// enforceBalance meters the instance, warns the owner when the remaining time crosses
+// a threshold and stops the instance once its balance is exhausted.
+func enforceBalance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance) error {
+	result, err := instance_billing.MeterInstance(appCtx, instance, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Free plans never run out
+	if result.CyclesPerHour <= 0 {
+		return nil
+	}
+
+	if result.Balance <= 0 {
+		err := instance_lifecycle.StopInstance(ctx, appCtx, instance, "metering", "cycles exhausted")
+		if err != nil && !errors.Is(err, instance_repositories.ErrIllegalTransition) {
+			return fmt.Errorf("failed to stop instance: %v", err)
+		}
+
+		// Billing ends here, the shutdown callback must not meter again
+		if _, err := appCtx.InstanceRepository.UpdateInstanceMeteredAt(instance, nil); err != nil {
+			return fmt.Errorf("failed to update metering: %v", err)
+		}
+
+		return notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindCyclesExhausted,
+			fmt.Sprintf("Instance %s ran out of cycles and is being stopped", notification_delivery.InstanceLabel(instance)))
+	}
+
+	thresholds := warningThresholds()
+
+	// Cycles were added since the last warning, warn again when they run low
+	if len(thresholds) > 0 && instance.CycleWarningMinutes != 0 && result.MinutesRemaining > float64(thresholds[len(thresholds)-1]) {
+		if err := appCtx.InstanceRepository.SetCycleWarningMinutes(instance, 0); err != nil {
+			return fmt.Errorf("failed to update instance: %v", err)
+		}
+	}
+
+	for _, threshold := range thresholds {
+		alreadyWarned := instance.CycleWarningMinutes != 0 && instance.CycleWarningMinutes <= threshold
+		if result.MinutesRemaining > float64(threshold) || alreadyWarned {
+			continue
+		}
+
+		if err := appCtx.InstanceRepository.SetCycleWarningMinutes(instance, threshold); err != nil {
+			return fmt.Errorf("failed to update instance: %v", err)
+		}
+
+		return notification_delivery.Notify(appCtx, instance, notification_models.NotificationKindCyclesLow,
+			fmt.Sprintf("Instance %s has about %d minutes of cycles left", notification_delivery.InstanceLabel(instance), int(result.MinutesRemaining)))
+	}
+
+	return nil
+}
+
+// warningThresholds returns METERING_WARNING_MINUTES as minutes, smallest first
+func warningThresholds() []int {
+	value := os.Getenv("METERING_WARNING_MINUTES")
+	if value == "" {
+		value = defaultWarningMinutes
+	}
+
+	var thresholds []int
+	for _, part := range strings.Split(value, ",") {
+		minutes, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && minutes > 0 {
+			thresholds = append(thresholds, minutes)
+		}
+	}
+	sort.Ints(thresholds)
+
+	return thresholds
+}
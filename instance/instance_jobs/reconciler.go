@@ -0,0 +1,293 @@
+package instance_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"gorm.io/gorm"
+)
+
+const (
+	// Records without a provider instance older than this were abandoned mid creation
+	provisioningTimeout = 10 * time.Minute
+
+	// Number of instance ids described per provider call
+	describeBatchSize = 100
+
+	// Records changed more recently than this are left alone
+	settleTime = 2 * time.Minute
+)
+
+// RunReconciler reconciles the instances table with the provider every interval
+// and deletes reports older than retention. It never returns.
+func RunReconciler(appCtx *app.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report := Reconcile(context.Background(), appCtx)
+		if report.Error != "" {
+			log.Printf("reconciler: Error: %s", report.Error)
+		} else if len(report.Items) > 0 {
+			log.Printf("reconciler: %d issues found in %d instances", len(report.Items), report.Checked)
+		}
+
+		if err := appCtx.ReconcileReportRepository.DeleteReconcileReportsBefore(time.Now().Add(-retention)); err != nil {
+			log.Printf("reconciler: Error: failed to delete old reports: %v", err)
+		}
+
+		<-ticker.C
+	}
+}
+
+// Reconcile compares every instance record with the provider, corrects drifted statuses,
+// relinks provider instances whose record lost its RealID and flags orphans.
+// The report is persisted even when reconciliation fails.
+func Reconcile(ctx context.Context, appCtx *app.Context) *instance_models.ReconcileReport {
+	report := &instance_models.ReconcileReport{Items: []instance_models.ReconcileItem{}}
+
+	if err := reconcile(ctx, appCtx, report); err != nil {
+		report.Error = err.Error()
+	}
+
+	if err := appCtx.ReconcileReportRepository.CreateReconcileReport(report); err != nil {
+		log.Printf("reconciler: Error: failed to save report: %v", err)
+	}
+
+	return report
+}
+
+func reconcile(ctx context.Context, appCtx *app.Context, report *instance_models.ReconcileReport) error {
+	instances, err := appCtx.InstanceRepository.GetInstances()
+	if err != nil {
+		return fmt.Errorf("failed to get instances: %v", err)
+	}
+	report.Checked = len(*instances)
+
+	// Describe every known provider instance
+	var realIDs []string
+	for _, instance := range *instances {
+		if instance.RealID != "" {
+			realIDs = append(realIDs, instance.RealID)
+		}
+	}
+
+	live := make(map[string]instance_aws.AWSInstance, len(realIDs))
+	for start := 0; start < len(realIDs); start += describeBatchSize {
+		batch := realIDs[start:min(start+describeBatchSize, len(realIDs))]
+		described, err := appCtx.InstanceClient.GetInstances(ctx, &batch)
+		if err != nil {
+			return fmt.Errorf("failed to describe instances: %v", err)
+		}
+		for _, instance := range *described {
+			live[instance.Id] = instance
+		}
+	}
+
+	// Find provider instances without a record
+	managed, err := appCtx.InstanceClient.GetManagedInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe managed instances: %v", err)
+	}
+
+	unlinked := make(map[uint]instance_aws.AWSInstance)
+	for _, awsInstance := range *managed {
+		if _, known := live[awsInstance.Id]; known {
+			continue
+		}
+
+		instanceID, err := strconv.ParseUint(awsInstance.Tags[instance_aws.TagInstanceID], 10, 32)
+		if err == nil {
+			unlinked[uint(instanceID)] = awsInstance
+			continue
+		}
+
+		if item := orphanItem(ctx, appCtx, awsInstance); item != nil {
+			report.Items = append(report.Items, *item)
+		}
+	}
+
+	for i := range *instances {
+		instance := &(*instances)[i]
+
+		// Only the snapshot of an archived instance exists, its terminated provider instance is not linked again
+		if instance.RealID == "" && instance.ArchiveBackupID != nil && instance.Status != instance_models.InstanceStatusProvisioning {
+			continue
+		}
+
+		if instance.RealID == "" {
+			if awsInstance, found := unlinked[instance.ID]; found {
+				delete(unlinked, instance.ID)
+				if err := appCtx.InstanceRepository.SetInstanceRealID(instance, awsInstance.Id); err != nil {
+					return fmt.Errorf("failed to relink instance %d: %v", instance.ID, err)
+				}
+				report.Items = append(report.Items, instance_models.ReconcileItem{
+					InstanceID: instance.ID,
+					RealID:     awsInstance.Id,
+					Issue:      instance_models.ReconcileIssueRelinked,
+					Detail:     "Provider instance created but never saved on the record",
+				})
+				live[awsInstance.Id] = awsInstance
+			} else {
+				if time.Since(instance.CreatedAt) > provisioningTimeout {
+					report.Items = append(report.Items, instance_models.ReconcileItem{
+						InstanceID: instance.ID,
+						Issue:      instance_models.ReconcileIssueProvisioning,
+						Detail:     "Record has no provider instance",
+					})
+				}
+				continue
+			}
+		}
+
+		// Give provider calls made by the handlers time to show up
+		if time.Since(instance.UpdatedAt) < settleTime {
+			continue
+		}
+
+		awsInstance, found := live[instance.RealID]
+		item, err := correctDrift(appCtx, instance, awsInstance, found)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			report.Items = append(report.Items, *item)
+		}
+	}
+
+	// Tagged with a record id that is not in the snapshot
+	for _, awsInstance := range unlinked {
+		if item := orphanItem(ctx, appCtx, awsInstance); item != nil {
+			report.Items = append(report.Items, *item)
+		}
+	}
+
+	return nil
+}
+
+// expectedStatuses lists the record statuses consistent with each provider state
+var expectedStatuses = map[types.InstanceStateName][]instance_models.InstanceStatus{
+	types.InstanceStateNamePending:  {instance_models.InstanceStatusProvisioning, instance_models.InstanceStatusStarting},
+	types.InstanceStateNameRunning:  {instance_models.InstanceStatusProvisioning, instance_models.InstanceStatusStarting, instance_models.InstanceStatusRunning, instance_models.InstanceStatusStopping, instance_models.InstanceStatusPendingDeletion},
+	types.InstanceStateNameStopping: {instance_models.InstanceStatusStopping, instance_models.InstanceStatusPendingDeletion},
+	types.InstanceStateNameStopped:  {instance_models.InstanceStatusStopped, instance_models.InstanceStatusResizing, instance_models.InstanceStatusRestoring, instance_models.InstanceStatusArchiving, instance_models.InstanceStatusPendingDeletion},
+}
+
+// correctedStatuses is the status forced on a record that does not match the provider state
+var correctedStatuses = map[types.InstanceStateName]instance_models.InstanceStatus{
+	types.InstanceStateNamePending:  instance_models.InstanceStatusStarting,
+	types.InstanceStateNameRunning:  instance_models.InstanceStatusStarting,
+	types.InstanceStateNameStopping: instance_models.InstanceStatusStopping,
+	types.InstanceStateNameStopped:  instance_models.InstanceStatusStopped,
+}
+
+// correctDrift aligns the record with the provider state. The record only becomes running
+// when the agent reports, so a provider instance that runs without it counts as starting.
+func correctDrift(appCtx *app.Context, instance *instance_models.Instance, awsInstance instance_aws.AWSInstance, found bool) (*instance_models.ReconcileItem, error) {
+	state := types.InstanceStateName(awsInstance.State)
+	item := &instance_models.ReconcileItem{
+		InstanceID: instance.ID,
+		RealID:     instance.RealID,
+		Issue:      instance_models.ReconcileIssueDrift,
+	}
+
+	var status instance_models.InstanceStatus
+	switch {
+	case !found || state == types.InstanceStateNameTerminated || state == types.InstanceStateNameShuttingDown:
+		// The deletion job finishes terminations that failed after the provider call
+		if instance.Status == instance_models.InstanceStatusTerminating || instance.Status == instance_models.InstanceStatusTerminated ||
+			instance.Status == instance_models.InstanceStatusFailed {
+			return nil, nil
+		}
+		status = instance_models.InstanceStatusFailed
+		item.Issue = instance_models.ReconcileIssueMissing
+		item.Detail = "Provider instance does not exist or is terminated"
+	case !slices.Contains(expectedStatuses[state], instance.Status):
+		status = correctedStatuses[state]
+		item.Detail = fmt.Sprintf("Status was %s but provider state is %s", instance.Status, state)
+	case instance.Status == instance_models.InstanceStatusRunning && instance.PublicIP != awsInstance.PublicIp:
+		item.Detail = fmt.Sprintf("Public IP changed from %q to %q", instance.PublicIP, awsInstance.PublicIp)
+		if err := appCtx.InstanceRepository.SetInstancePublicIP(instance, awsInstance.PublicIp); err != nil {
+			return nil, fmt.Errorf("failed to correct instance %d: %v", instance.ID, err)
+		}
+		appCtx.Events.Publish(instance.UserID, instance.ID, instance_events.EventIP, instance_events.IPData{PublicIP: instance.PublicIP})
+		return item, nil
+	default:
+		return nil, nil
+	}
+
+	if err := appCtx.InstanceRepository.ForceInstanceStatus(instance, status, "reconciler", item.Detail); err != nil {
+		return nil, fmt.Errorf("failed to correct instance %d: %v", instance.ID, err)
+	}
+
+	// Not running anymore, bill the time since the last pass, stop billing and forget the address
+	if status != instance_models.InstanceStatusStarting {
+		if err := appCtx.InstanceRepository.SetInstancePublicIP(instance, ""); err != nil {
+			return nil, fmt.Errorf("failed to correct instance %d: %v", instance.ID, err)
+		}
+		if instance.MeteredAt != nil {
+			if _, err := instance_billing.MeterInstance(appCtx, instance, time.Now()); err != nil {
+				return nil, fmt.Errorf("failed to meter instance %d: %v", instance.ID, err)
+			}
+		}
+		if _, err := appCtx.InstanceRepository.UpdateInstanceMeteredAt(instance, nil); err != nil {
+			return nil, fmt.Errorf("failed to correct instance %d: %v", instance.ID, err)
+		}
+		appCtx.Events.Publish(instance.UserID, instance.ID, instance_events.EventIP, instance_events.IPData{})
+	}
+
+	return item, nil
+}
+
+// orphanItem flags a provider instance without a record and terminates it
+// when RECONCILER_TERMINATE_ORPHANS is "true". The instances snapshot is read before
+// the provider is listed, so the record of a tagged instance is looked up again and
+// nil is returned when it was created in the meantime. Instances launched within
+// provisioningTimeout are never terminated.
+func orphanItem(ctx context.Context, appCtx *app.Context, awsInstance instance_aws.AWSInstance) *instance_models.ReconcileItem {
+	item := &instance_models.ReconcileItem{
+		RealID: awsInstance.Id,
+		Issue:  instance_models.ReconcileIssueOrphan,
+		Detail: fmt.Sprintf("No record for provider instance in state %s", awsInstance.State),
+	}
+
+	if instanceID, err := strconv.ParseUint(awsInstance.Tags[instance_aws.TagInstanceID], 10, 32); err == nil {
+		_, err := appCtx.InstanceRepository.GetInstance(uint(instanceID))
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			item.Detail += fmt.Sprintf(", record lookup failed: %v", err)
+			return item
+		}
+	}
+
+	if os.Getenv("RECONCILER_TERMINATE_ORPHANS") != "true" {
+		return item
+	}
+
+	if time.Since(awsInstance.LaunchTime) < provisioningTimeout {
+		item.Detail += ", launched too recently to terminate"
+		return item
+	}
+
+	if err := appCtx.InstanceClient.TerminateInstances(ctx, []string{awsInstance.Id}); err != nil {
+		item.Detail += fmt.Sprintf(", termination failed: %v", err)
+	} else {
+		item.Detail += ", terminated"
+	}
+
+	return item
+}
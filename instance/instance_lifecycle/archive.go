@@ -0,0 +1,139 @@
+package instance_lifecycle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/backup/backup_lifecycle"
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+)
+
+// ArchiveInstance moves a stopped instance to archiving and snapshots its disk. Once the snapshot
+// completes, FinishArchive terminates the provider instance so only the snapshot is paid for.
+// Returns instance_repositories.ErrIllegalTransition when the instance is not stopped.
+func ArchiveInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, actor string, reason string) error {
+	previous := instance.Status
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusArchiving, actor, reason); err != nil {
+		return err
+	}
+
+	backup, err := backup_lifecycle.TakeBackup(ctx, appCtx, instance, false, "archive", actor)
+	if err != nil {
+		revert(appCtx, instance, previous, err)
+		return err
+	}
+
+	if err := appCtx.InstanceRepository.SetInstanceArchive(instance, &backup.ID); err != nil {
+		revert(appCtx, instance, previous, err)
+		return err
+	}
+
+	return nil
+}
+
+// FinishArchive terminates the provider instance of an archiving instance once its archive
+// snapshot completed and marks it archived. If the snapshot failed, the instance is stopped again.
+// It reports whether the instance was archived.
+func FinishArchive(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance) (bool, error) {
+	if instance.ArchiveBackupID == nil {
+		return false, appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, "archiver", "archive backup missing")
+	}
+
+	backup, err := appCtx.BackupRepository.GetBackup(*instance.ArchiveBackupID)
+	if err != nil {
+		return false, err
+	}
+
+	switch backup.Status {
+	case backup_models.BackupStatusPending:
+		return false, nil
+	case backup_models.BackupStatusFailed:
+		if err := appCtx.InstanceRepository.SetInstanceArchive(instance, nil); err != nil {
+			return false, err
+		}
+		return false, appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopped, "archiver", "archive backup failed")
+	}
+
+	if err := appCtx.InstanceClient.TerminateInstances(ctx, []string{instance.RealID}); err != nil {
+		return false, err
+	}
+
+	// The agent comes back with a new credential when the instance is unarchived
+	if err := appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID); err != nil {
+		return false, err
+	}
+
+	if err := appCtx.InstanceRepository.SetInstanceRealID(instance, ""); err != nil {
+		return false, err
+	}
+	if err := appCtx.InstanceRepository.SetInstancePublicIP(instance, ""); err != nil {
+		return false, err
+	}
+
+	reason := fmt.Sprintf("disk kept in backup %d", backup.ID)
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusArchived, "archiver", reason); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UnarchiveInstance launches a new provider instance of the archived instance's plan with its
+// disk restored from the archive. It provisions like a new instance. The archive backup is
+// deleted once the instance runs.
+// Returns instance_repositories.ErrIllegalTransition when the instance is not archived.
+func UnarchiveInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, actor string) error {
+	if instance.Status != instance_models.InstanceStatusArchived || instance.ArchiveBackupID == nil {
+		return fmt.Errorf("%w: instance is not archived", instance_repositories.ErrIllegalTransition)
+	}
+
+	backup, err := appCtx.BackupRepository.GetBackup(*instance.ArchiveBackupID)
+	if err != nil {
+		return fmt.Errorf("failed to get archive backup: %v", err)
+	}
+
+	plan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
+	if err != nil {
+		return fmt.Errorf("failed to get plan: %v", err)
+	}
+
+	instanceType, err := instance_aws.ParseInstanceType(plan.InstanceType)
+	if err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("unarchiving from backup %d", backup.ID)
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusProvisioning, actor, reason); err != nil {
+		return err
+	}
+
+	secret, err := appCtx.InstanceCredentialsRepository.IssueInstanceCredential(instance.ID)
+	if err != nil {
+		revert(appCtx, instance, instance_models.InstanceStatusArchived, err)
+		return err
+	}
+
+	options := LaunchOptions(instance, secret, int32(plan.Disk))
+	options.SnapshotID = backup.SnapshotID
+
+	ec2Instance, err := appCtx.InstanceClient.CreateInstance(ctx, &instanceType, options)
+	if err != nil {
+		appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID)
+		revert(appCtx, instance, instance_models.InstanceStatusArchived, err)
+		return err
+	}
+
+	if err := appCtx.InstanceRepository.SetInstanceRealID(instance, ec2Instance.Id); err != nil {
+		appCtx.InstanceClient.TerminateInstances(ctx, []string{ec2Instance.Id})
+		appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID)
+		revert(appCtx, instance, instance_models.InstanceStatusArchived, err)
+		return err
+	}
+
+	return nil
+}
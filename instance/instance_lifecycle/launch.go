@@ -0,0 +1,26 @@
+package instance_lifecycle
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// LaunchOptions are the provider options for a new EC2 instance of the record.
+// The agent authenticates its callbacks with secret.
+func LaunchOptions(instance *instance_models.Instance, secret string, diskSize int32) *instance_aws.CreateInstanceOptions {
+	return &instance_aws.CreateInstanceOptions{
+		AgentEnv: map[string]string{
+			"GSHUB_INSTANCE_ID":     strconv.FormatUint(uint64(instance.ID), 10),
+			"GSHUB_INSTANCE_SECRET": secret,
+			"GSHUB_CALLBACK_URL":    os.Getenv("INSTANCE_CALLBACK_URL"),
+		},
+		Tags: map[string]string{
+			instance_aws.TagInstanceID: strconv.FormatUint(uint64(instance.ID), 10),
+			instance_aws.TagUserID:     strconv.FormatUint(uint64(instance.UserID), 10),
+		},
+		DiskSize: diskSize,
+	}
+}
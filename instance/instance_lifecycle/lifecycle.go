@@ -0,0 +1,122 @@
+package instance_lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// StartInstance moves a stopped or failed instance to starting and starts it with the provider.
+// Returns instance_repositories.ErrIllegalTransition when the instance cannot be started.
+func StartInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, actor string, reason string) error {
+	previous := instance.Status
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStarting, actor, reason); err != nil {
+		return err
+	}
+
+	if err := appCtx.InstanceClient.StartInstances(ctx, []string{instance.RealID}); err != nil {
+		revert(appCtx, instance, previous, err)
+		return err
+	}
+
+	return nil
+}
+
+// StopInstance moves a running, starting or failed instance to stopping and stops it with the provider.
+// Returns instance_repositories.ErrIllegalTransition when the instance cannot be stopped.
+func StopInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, actor string, reason string) error {
+	previous := instance.Status
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusStopping, actor, reason); err != nil {
+		return err
+	}
+
+	if err := appCtx.InstanceClient.StopInstances(ctx, []string{instance.RealID}); err != nil {
+		revert(appCtx, instance, previous, err)
+		return err
+	}
+
+	return nil
+}
+
+// TerminateInstance terminates the instance with the provider, revokes the agent credentials
+// and deletes the record. When the provider call fails the instance keeps its previous status
+// so a pending deletion is retried. A failure after the provider call leaves the instance
+// terminating or terminated, calling it again continues from there.
+// Returns instance_repositories.ErrIllegalTransition when the instance cannot be terminated.
+func TerminateInstance(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, actor string, reason string) error {
+	switch instance.Status {
+	case instance_models.InstanceStatusTerminated:
+		// Only the record is left
+		return appCtx.InstanceRepository.DeleteInstance(instance.ID)
+	case instance_models.InstanceStatusTerminating:
+		// The provider already terminated it
+	default:
+		previous := instance.Status
+
+		if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusTerminating, actor, reason); err != nil {
+			return err
+		}
+
+		if instance.RealID != "" {
+			if err := appCtx.InstanceClient.TerminateInstances(ctx, []string{instance.RealID}); err != nil {
+				revert(appCtx, instance, previous, err)
+				return err
+			}
+		}
+	}
+
+	// Bill the running time the agent will not report anymore
+	if instance.MeteredAt != nil {
+		if _, err := instance_billing.MeterInstance(appCtx, instance, time.Now()); err != nil {
+			return err
+		}
+		if _, err := appCtx.InstanceRepository.UpdateInstanceMeteredAt(instance, nil); err != nil {
+			return err
+		}
+	}
+
+	// The agent must not be able to call back anymore
+	if err := appCtx.InstanceCredentialsRepository.RevokeInstanceCredentials(instance.ID); err != nil {
+		return err
+	}
+
+	if err := appCtx.InstanceRepository.TransitionInstance(instance, instance_models.InstanceStatusTerminated, actor, reason); err != nil {
+		return err
+	}
+
+	return appCtx.InstanceRepository.DeleteInstance(instance.ID)
+}
+
+// ScheduleTermination moves the instance to pending deletion. It is stopped right away and
+// terminated by the deletion job once the grace period ends, unless the deletion is cancelled.
+// Returns instance_repositories.ErrIllegalTransition when the instance cannot be terminated.
+func ScheduleTermination(ctx context.Context, appCtx *app.Context, instance *instance_models.Instance, grace time.Duration, finalBackup bool, actor string) error {
+	previous := instance.Status
+	deleteAt := time.Now().Add(grace)
+
+	reason := "termination requested, deleted at " + deleteAt.UTC().Format(time.RFC3339)
+	if err := appCtx.InstanceRepository.ScheduleInstanceDeletion(instance, deleteAt, finalBackup, actor, reason); err != nil {
+		return err
+	}
+
+	// The agent shutdown bills the last running time as usual
+	if previous != instance_models.InstanceStatusStopped && previous != instance_models.InstanceStatusFailed && instance.RealID != "" {
+		if err := appCtx.InstanceClient.StopInstances(ctx, []string{instance.RealID}); err != nil {
+			revert(appCtx, instance, previous, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revert puts the instance back into the status it had before a provider call failed
+func revert(appCtx *app.Context, instance *instance_models.Instance, previous instance_models.InstanceStatus, cause error) {
+	appCtx.InstanceRepository.ForceInstanceStatus(instance, previous, "system", fmt.Sprintf("provider call failed: %v", cause))
+}
@@ -0,0 +1,52 @@
+package instance_middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// RequireInstanceCredential authenticates instance agent callbacks.
+//
+// The agent must send the secret it received in its user data as a bearer token,
+// and the secret must belong to the instance identified by the :id route parameter.
+func RequireInstanceCredential(appCtx *app.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		instanceIDStr := c.Param("id")
+		instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+		if err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, instanceIDStr)
+			c.Abort()
+			return
+		}
+
+		secret, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || secret == "" {
+			utils.HandleError(c, http.StatusUnauthorized, "Missing instance credential", errors.New("no bearer token"), instanceIDStr)
+			c.Abort()
+			return
+		}
+
+		credential, err := appCtx.InstanceCredentialsRepository.VerifyInstanceCredential(uint(instanceID64), secret)
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to verify instance credential", err, instanceIDStr)
+			c.Abort()
+			return
+		}
+
+		if credential == nil {
+			utils.HandleError(c, http.StatusUnauthorized, "Invalid instance credential", errors.New("credential mismatch or revoked"), instanceIDStr)
+			c.Abort()
+			return
+		}
+
+		// Rotations keep the credential the agent used until the new one is used
+		c.Set("instanceCredentialID", credential.ID)
+		c.Next()
+	}
+}
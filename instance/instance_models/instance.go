@@ -13,14 +13,36 @@ type Instance struct {
 	UpdatedAt time.Time      `json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
 	RealID    string         `gorm:"not null" json:"realId"`
-	Name      string         `json:"name"`
 
-	Ready    bool   `json:"ready"`
-	PublicIP string `json:"publicIp"`
+	Name        string `gorm:"uniqueIndex:idx_instance_user_name,where:deleted_at IS NULL AND name <> ''" json:"name"` // Unique per user when set
+	Description string `json:"description"`
 
-	PlanID uint `gorm:"not null" json:"planId"` // Reference to the plan
-	UserID uint `gorm:"not null" json:"userId"` // Reference to the user
+	Status   InstanceStatus `gorm:"not null;default:'stopped';index" json:"status"`
+	Ready    bool           `json:"ready"` // Same as Status being running
+	PublicIP string         `json:"publicIp"`
 
-	Cycles       []InstanceCycle       `json:"cycles"`
-	BurnedCycles []InstanceBurnedCycle `json:"burnedCycles"`
+	Health          InstanceHealth `gorm:"not null;default:'unknown'" json:"health"`
+	LastHeartbeatAt *time.Time     `json:"lastHeartbeatAt"` // Last contact from the agent of the current run
+
+	MeteredAt           *time.Time `json:"meteredAt"` // Start of the running time not billed yet, nil when not running
+	CycleWarningMinutes int        `json:"-"`         // Lowest low-cycles warning threshold already sent
+
+	IdleStopMinutes int `gorm:"not null;default:0" json:"idleStopMinutes"` // Stop the instance after this long without players, 0 to keep it running
+
+	DeletionScheduledAt *time.Time `gorm:"index" json:"deletionScheduledAt"`          // End of the grace period of a pending deletion
+	FinalBackup         bool       `gorm:"not null;default:false" json:"finalBackup"` // Back up the disk before the pending deletion terminates it
+	FinalBackupID       *uint      `json:"finalBackupId"`                             // Final backup already taken, reused when the termination is retried
+
+	ArchiveBackupID *uint `json:"archiveBackupId"` // Backup holding the disk while archived, deleted once the unarchived instance runs
+
+	PlanID    uint `gorm:"not null" json:"planId"`                                    // Reference to the plan
+	UserID    uint `gorm:"not null;uniqueIndex:idx_instance_user_name" json:"userId"` // Reference to the user
+	ServiceID uint `gorm:"not null;default:0" json:"serviceId"`                       // Reference to the service running on the instance
+
+	ServicePresetID uint `gorm:"not null;default:0" json:"servicePresetId"` // Preset version the instance was created with, 0 for the published one
+
+	WalletID *uint `gorm:"index" json:"walletId"` // Wallet the instance burns cycles from, nil to use its own balance
+
+	Env  []InstanceEnv `json:"env"`
+	Tags []InstanceTag `json:"tags"`
 }
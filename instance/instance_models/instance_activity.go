@@ -0,0 +1,18 @@
+package instance_models
+
+import (
+	"time"
+)
+
+// InstanceActivity is the latest heartbeat reported by the agent of an instance
+type InstanceActivity struct {
+	InstanceID     uint       `gorm:"primaryKey;autoIncrement:false" json:"instanceId"`
+	ReportedAt     time.Time  `gorm:"not null" json:"reportedAt"`
+	PlayerCount    int        `json:"playerCount"`
+	CPUPercent     float64    `json:"cpuPercent"`
+	MemoryUsedMiB  int        `json:"memoryUsedMiB"`
+	DiskUsedMiB    int        `json:"diskUsedMiB"`
+	AgentVersion   string     `json:"agentVersion"`
+	ContainerState string     `json:"containerState"` // State of the service container as seen by the agent, e.g. running or exited
+	IdleSince      *time.Time `json:"idleSince"`      // First heartbeat without players since the last one with players, nil while players are online
+}
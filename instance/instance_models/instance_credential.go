@@ -0,0 +1,16 @@
+package instance_models
+
+import (
+	"time"
+)
+
+// InstanceCredential is a secret the instance agent uses to authenticate its callbacks.
+// Only the SHA-256 hash of the secret is stored.
+type InstanceCredential struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	InstanceID uint       `gorm:"not null;index" json:"instanceId"`
+	SecretHash string     `gorm:"not null" json:"-"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+}
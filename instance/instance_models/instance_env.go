@@ -0,0 +1,15 @@
+package instance_models
+
+import (
+	"time"
+)
+
+// InstanceEnv is an environment variable value chosen by the user for the instance's service
+type InstanceEnv struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	InstanceID uint      `gorm:"not null;uniqueIndex:idx_instance_env_key" json:"instanceId"`
+	Key        string    `gorm:"not null;uniqueIndex:idx_instance_env_key" json:"key"`
+	Value      string    `json:"value"`
+}
@@ -0,0 +1,10 @@
+package instance_models
+
+// InstanceHealth tells whether the agent of a running instance is responsive
+type InstanceHealth string
+
+const (
+	InstanceHealthUnknown   InstanceHealth = "unknown" // Not running or no heartbeat yet
+	InstanceHealthHealthy   InstanceHealth = "healthy"
+	InstanceHealthUnhealthy InstanceHealth = "unhealthy" // Heartbeats stopped or the service container is down
+)
@@ -0,0 +1,50 @@
+package instance_models
+
+import (
+	"time"
+)
+
+type InstanceRole string
+
+const (
+	InstanceRoleOwner    InstanceRole = "owner"    // The user the instance belongs to, manages members, plan and termination
+	InstanceRoleOperator InstanceRole = "operator" // Starts and stops the instance and changes its configuration
+	InstanceRoleViewer   InstanceRole = "viewer"   // Sees the instance, its status and its activity
+)
+
+// instanceRoleRanks orders the roles, a role has every permission of the roles ranked below it
+var instanceRoleRanks = map[InstanceRole]int{
+	InstanceRoleViewer:   1,
+	InstanceRoleOperator: 2,
+	InstanceRoleOwner:    3,
+}
+
+// Includes reports whether the role has the permissions of another role
+func (r InstanceRole) Includes(other InstanceRole) bool {
+	return instanceRoleRanks[r] > 0 && instanceRoleRanks[r] >= instanceRoleRanks[other]
+}
+
+// InstanceMember gives a user other than the owner access to the instance. The owner
+// is the user of the instance and has no member record.
+type InstanceMember struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	InstanceID uint         `gorm:"not null;uniqueIndex:idx_instance_member" json:"instanceId"`
+	UserID     uint         `gorm:"not null;uniqueIndex:idx_instance_member;index" json:"userId"`
+	Role       InstanceRole `gorm:"not null" json:"role"`
+	InvitedBy  string       `json:"invitedBy"`
+}
+
+// InstanceInvite lets whoever signs in with the invited email join the instance with the role
+type InstanceInvite struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time    `json:"createdAt"`
+	InstanceID uint         `gorm:"not null;index" json:"instanceId"`
+	Email      string       `gorm:"not null" json:"email"`
+	Role       InstanceRole `gorm:"not null" json:"role"`
+	TokenHash  string       `gorm:"not null;uniqueIndex" json:"-"` // SHA-256 of the token in the invite link
+	InvitedBy  string       `gorm:"not null" json:"invitedBy"`
+	ExpiresAt  time.Time    `gorm:"not null" json:"expiresAt"`
+	AcceptedAt *time.Time   `json:"acceptedAt"`
+	RevokedAt  *time.Time   `json:"revokedAt"`
+}
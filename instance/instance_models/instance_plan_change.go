@@ -0,0 +1,18 @@
+package instance_models
+
+import (
+	"time"
+)
+
+// InstancePlanChange records a plan change of an instance.
+// Running time after CreatedAt is billed at ToPrice.
+type InstancePlanChange struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	InstanceID uint      `gorm:"not null;index" json:"instanceId"`
+	FromPlanID uint      `gorm:"not null" json:"fromPlanId"`
+	ToPlanID   uint      `gorm:"not null" json:"toPlanId"`
+	FromPrice  float64   `gorm:"not null" json:"fromPrice"`
+	ToPrice    float64   `gorm:"not null" json:"toPrice"`
+	Actor      string    `gorm:"not null" json:"actor"`
+}
@@ -0,0 +1,61 @@
+package instance_models
+
+import (
+	"time"
+)
+
+type InstanceStatus string
+
+const (
+	InstanceStatusProvisioning    InstanceStatus = "provisioning" // Created, waiting for the first agent startup
+	InstanceStatusStarting        InstanceStatus = "starting"     // Start requested, waiting for the agent startup
+	InstanceStatusRunning         InstanceStatus = "running"      // Agent reported the service is up
+	InstanceStatusStopping        InstanceStatus = "stopping"     // Stop requested, waiting for the agent shutdown
+	InstanceStatusStopped         InstanceStatus = "stopped"
+	InstanceStatusResizing        InstanceStatus = "resizing"         // Plan change in progress
+	InstanceStatusRestoring       InstanceStatus = "restoring"        // Root volume being replaced with a backup
+	InstanceStatusArchiving       InstanceStatus = "archiving"        // Disk being snapshotted before the provider instance is terminated
+	InstanceStatusArchived        InstanceStatus = "archived"         // Only the snapshot of the disk is kept, unarchive to use the instance again
+	InstanceStatusPendingDeletion InstanceStatus = "pending_deletion" // Termination requested, cancellable until the grace period ends
+	InstanceStatusTerminating     InstanceStatus = "terminating"
+	InstanceStatusTerminated      InstanceStatus = "terminated"
+	InstanceStatusFailed          InstanceStatus = "failed" // Provider or agent error, needs a start, stop or terminate
+)
+
+// instanceTransitions lists the statuses reachable from each status
+var instanceTransitions = map[InstanceStatus][]InstanceStatus{
+	InstanceStatusProvisioning:    {InstanceStatusRunning, InstanceStatusStopping, InstanceStatusStopped, InstanceStatusPendingDeletion, InstanceStatusTerminating, InstanceStatusFailed},
+	InstanceStatusStarting:        {InstanceStatusRunning, InstanceStatusStopping, InstanceStatusStopped, InstanceStatusPendingDeletion, InstanceStatusTerminating, InstanceStatusFailed},
+	InstanceStatusRunning:         {InstanceStatusStopping, InstanceStatusStopped, InstanceStatusPendingDeletion, InstanceStatusTerminating, InstanceStatusFailed},
+	InstanceStatusStopping:        {InstanceStatusStopped, InstanceStatusPendingDeletion, InstanceStatusTerminating, InstanceStatusFailed},
+	InstanceStatusStopped:         {InstanceStatusStarting, InstanceStatusResizing, InstanceStatusRestoring, InstanceStatusArchiving, InstanceStatusPendingDeletion, InstanceStatusTerminating},
+	InstanceStatusResizing:        {InstanceStatusStopped, InstanceStatusFailed},
+	InstanceStatusRestoring:       {InstanceStatusStopped, InstanceStatusFailed},
+	InstanceStatusArchiving:       {InstanceStatusArchived, InstanceStatusStopped, InstanceStatusFailed},
+	InstanceStatusArchived:        {InstanceStatusProvisioning, InstanceStatusPendingDeletion, InstanceStatusTerminating},
+	InstanceStatusPendingDeletion: {InstanceStatusStopped, InstanceStatusArchived, InstanceStatusTerminating},
+	InstanceStatusTerminating:     {InstanceStatusTerminated, InstanceStatusFailed},
+	InstanceStatusTerminated:      {},
+	InstanceStatusFailed:          {InstanceStatusStarting, InstanceStatusRunning, InstanceStatusStopping, InstanceStatusStopped, InstanceStatusPendingDeletion, InstanceStatusTerminating},
+}
+
+// CanTransition reports whether an instance may move from one status to another
+func CanTransition(from InstanceStatus, to InstanceStatus) bool {
+	for _, status := range instanceTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceStatusChange records a status transition of an instance
+type InstanceStatusChange struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	InstanceID uint           `gorm:"not null;index" json:"instanceId"`
+	From       InstanceStatus `json:"from"`
+	To         InstanceStatus `gorm:"not null" json:"to"`
+	Actor      string         `gorm:"not null" json:"actor"` // Email of the user or name of the component
+	Reason     string         `json:"reason"`
+}
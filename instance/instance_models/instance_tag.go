@@ -0,0 +1,13 @@
+package instance_models
+
+import (
+	"time"
+)
+
+// InstanceTag is a free-form label the user attached to the instance
+type InstanceTag struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt  time.Time `json:"-"`
+	InstanceID uint      `gorm:"not null;uniqueIndex:idx_instance_tag" json:"-"`
+	Tag        string    `gorm:"not null;uniqueIndex:idx_instance_tag;index" json:"tag"`
+}
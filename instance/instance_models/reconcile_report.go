@@ -0,0 +1,31 @@
+package instance_models
+
+import (
+	"time"
+)
+
+type ReconcileIssue string
+
+const (
+	ReconcileIssueDrift        ReconcileIssue = "drift"        // Record did not match the provider state and was corrected
+	ReconcileIssueMissing      ReconcileIssue = "missing"      // Record points at an instance the provider does not have
+	ReconcileIssueOrphan       ReconcileIssue = "orphan"       // Provider instance without a record
+	ReconcileIssueRelinked     ReconcileIssue = "relinked"     // Provider instance was reattached to its record
+	ReconcileIssueProvisioning ReconcileIssue = "provisioning" // Record never received a provider instance
+)
+
+type ReconcileItem struct {
+	InstanceID uint           `json:"instanceId,omitempty"`
+	RealID     string         `json:"realId,omitempty"`
+	Issue      ReconcileIssue `json:"issue"`
+	Detail     string         `json:"detail"`
+}
+
+// ReconcileReport is the outcome of one comparison between the instances table and the provider
+type ReconcileReport struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Checked   int             `json:"checked"`
+	Items     []ReconcileItem `gorm:"serializer:json" json:"items"`
+	Error     string          `json:"error,omitempty"`
+}
@@ -2,30 +2,124 @@ package instance_repositories
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrIllegalTransition = errors.New("illegal instance status transition")
+	ErrInstanceNameTaken = errors.New("instance name already in use")
+)
+
+// InstanceFilter narrows and orders a user's instance list. Zero values match everything.
+type InstanceFilter struct {
+	Name      string   // Case insensitive substring of the name
+	Tags      []string // The instance must have every tag
+	ServiceID uint
+	PlanID    uint
+	Status    instance_models.InstanceStatus
+	Sort      string // One of instanceSortColumns, prefixed with "-" for descending order
+}
+
+// instanceSortColumns maps the sort keys accepted in InstanceFilter.Sort to columns
+var instanceSortColumns = map[string]string{
+	"name":      "name",
+	"createdAt": "created_at",
+	"status":    "status",
+	"planId":    "plan_id",
+	"serviceId": "service_id",
+}
+
 type InstanceRepository struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	Events *instance_events.Hub // Receives status changes, may be nil
 }
 
 func NewInstanceRepository(db *gorm.DB) *InstanceRepository {
 	return &InstanceRepository{DB: db}
 }
 
+func (r *InstanceRepository) GetInstances() (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	if err := r.DB.Find(&instances).Error; err != nil {
+		return nil, err
+	}
+	return &instances, nil
+}
+
+// GetMeteredInstances returns the instances whose running time is being billed
+func (r *InstanceRepository) GetMeteredInstances() (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	if err := r.DB.Where("metered_at IS NOT NULL").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+	return &instances, nil
+}
+
 func (r *InstanceRepository) GetUserInstances(userID uint) (*[]instance_models.Instance, error) {
 	var instances []instance_models.Instance
-	if err := r.DB.Where("user_id = ?", userID).Find(&instances).Error; err != nil {
+	if err := r.DB.Preload("Tags").Where("user_id = ?", userID).Find(&instances).Error; err != nil {
 		return nil, err
 	}
 	return &instances, nil
 }
 
+// FindUserInstances returns a page of the user's instances matching the filter and the total number of matches
+func (r *InstanceRepository) FindUserInstances(userID uint, filter InstanceFilter, page int, pageSize int) (*[]instance_models.Instance, int64, error) {
+	query := r.DB.Model(&instance_models.Instance{}).Where("user_id = ?", userID)
+
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+escapeLike(filter.Name)+"%")
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where("EXISTS (SELECT 1 FROM instance_tags WHERE instance_tags.instance_id = instances.id AND instance_tags.tag = ?)", tag)
+	}
+	if filter.ServiceID != 0 {
+		query = query.Where("service_id = ?", filter.ServiceID)
+	}
+	if filter.PlanID != 0 {
+		query = query.Where("plan_id = ?", filter.PlanID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at desc"
+	if column, ok := instanceSortColumns[strings.TrimPrefix(filter.Sort, "-")]; ok {
+		order = column
+		if strings.HasPrefix(filter.Sort, "-") {
+			order += " desc"
+		}
+	}
+
+	var instances []instance_models.Instance
+	err := query.Preload("Tags").
+		Order(order).
+		Order("id").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&instances).Error
+	return &instances, total, err
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
 func (r *InstanceRepository) GetUserInstance(userEmail string, instanceID uint) (*instance_models.Instance, error) {
 	var instance instance_models.Instance
-	err := r.DB.Where("id = ? AND user_id = (SELECT id FROM users WHERE email = ?)", instanceID, userEmail).First(&instance).Error
+	err := r.DB.Preload("Tags").Where("id = ? AND user_id = (SELECT id FROM users WHERE email = ?)", instanceID, userEmail).First(&instance).Error
 	return &instance, err
 }
 
@@ -35,8 +129,130 @@ func (r *InstanceRepository) GetInstance(instanceID uint) (*instance_models.Inst
 	return &instance, err
 }
 
-func (r *InstanceRepository) CreateInstance(instance *instance_models.Instance) error {
-	return r.DB.Create(instance).Error
+// CreateInstance creates the instance and records its initial status.
+// ErrInstanceNameTaken is returned if the user has another instance with the same name.
+func (r *InstanceRepository) CreateInstance(instance *instance_models.Instance, actor string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkInstanceName(tx, instance.UserID, instance.Name, 0); err != nil {
+			return err
+		}
+
+		if err := tx.Create(instance).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&instance_models.InstanceStatusChange{
+			InstanceID: instance.ID,
+			To:         instance.Status,
+			Actor:      actor,
+			Reason:     "created",
+		}).Error
+	})
+}
+
+// TransitionInstance moves the instance to a new status if the transition table allows it
+// and nobody changed the status concurrently. Otherwise ErrIllegalTransition is returned.
+func (r *InstanceRepository) TransitionInstance(instance *instance_models.Instance, to instance_models.InstanceStatus, actor string, reason string) error {
+	if !instance_models.CanTransition(instance.Status, to) {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, instance.Status, to)
+	}
+	return r.setInstanceStatus(instance, to, actor, reason)
+}
+
+// ForceInstanceStatus sets the status regardless of the transition table, used to align
+// the record with what the provider reports
+func (r *InstanceRepository) ForceInstanceStatus(instance *instance_models.Instance, to instance_models.InstanceStatus, actor string, reason string) error {
+	return r.setInstanceStatus(instance, to, actor, reason)
+}
+
+func (r *InstanceRepository) setInstanceStatus(instance *instance_models.Instance, to instance_models.InstanceStatus, actor string, reason string) error {
+	from := instance.Status
+
+	// Health only describes running instances
+	updates := map[string]interface{}{
+		"status": to,
+		"ready":  to == instance_models.InstanceStatusRunning,
+	}
+	if to != instance_models.InstanceStatusRunning {
+		updates["health"] = instance_models.InstanceHealthUnknown
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&instance_models.Instance{}).
+			Where("id = ? AND status = ?", instance.ID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: status changed concurrently", ErrIllegalTransition)
+		}
+
+		return tx.Create(&instance_models.InstanceStatusChange{
+			InstanceID: instance.ID,
+			From:       from,
+			To:         to,
+			Actor:      actor,
+			Reason:     reason,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	instance.Status = to
+	instance.Ready = to == instance_models.InstanceStatusRunning
+	if to != instance_models.InstanceStatusRunning {
+		instance.Health = instance_models.InstanceHealthUnknown
+	}
+
+	r.Events.Publish(instance.UserID, instance.ID, instance_events.EventStatus, instance_events.StatusData{
+		From:   string(from),
+		To:     string(to),
+		Ready:  instance.Ready,
+		Actor:  actor,
+		Reason: reason,
+	})
+	return nil
+}
+
+// ChangeInstancePlan points the instance at a new plan and records the change
+func (r *InstanceRepository) ChangeInstancePlan(instance *instance_models.Instance, change *instance_models.InstancePlanChange) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(instance).Update("plan_id", change.ToPlanID).Error; err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (r *InstanceRepository) GetInstanceStatusHistory(instanceID uint, page int, pageSize int) (*[]instance_models.InstanceStatusChange, int64, error) {
+	query := r.DB.Model(&instance_models.InstanceStatusChange{}).Where("instance_id = ?", instanceID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var changes []instance_models.InstanceStatusChange
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&changes).Error
+	return &changes, total, err
+}
+
+// DeleteInstance deletes the instance and the access others had to it
+func (r *InstanceRepository) DeleteInstance(instanceID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&instance_models.InstanceInvite{}).
+			Where("instance_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", instanceID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&instance_models.Instance{}, instanceID).Error
+	})
 }
 
 func (r *InstanceRepository) DeleteUserInstance(userEmail string, instanceID uint) error {
@@ -52,6 +268,138 @@ func (r *InstanceRepository) DeleteUserInstance(userEmail string, instanceID uin
 	return nil
 }
 
+// PurgeInstance permanently removes an instance record that never became usable
+func (r *InstanceRepository) PurgeInstance(instanceID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceEnv{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceStatusChange{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceActivity{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&instance_models.Instance{}, instanceID).Error
+	})
+}
+
+// SaveInstance saves every field except the status, which only changes through TransitionInstance
+// and ForceInstanceStatus so a stale copy cannot overwrite it. The same goes for the health, the
+// funding wallet, the metering state, the pending deletion and the archive, which have their own
+// methods like env and tags. Jobs use the targeted methods only.
 func (r *InstanceRepository) SaveInstance(instance *instance_models.Instance) error {
-	return r.DB.Save(instance).Error
+	return r.DB.Omit("status", "ready", "health", "last_heartbeat_at", "wallet_id", "metered_at", "cycle_warning_minutes",
+		"deletion_scheduled_at", "final_backup", "final_backup_id", "archive_backup_id", clause.Associations).Save(instance).Error
+}
+
+// SetInstanceWallet makes the instance burn cycles from the wallet, nil switches back to its own balance
+func (r *InstanceRepository) SetInstanceWallet(instance *instance_models.Instance, walletID *uint) error {
+	if err := r.DB.Model(instance).Update("wallet_id", walletID).Error; err != nil {
+		return err
+	}
+
+	instance.WalletID = walletID
+	return nil
+}
+
+// UpdateInstanceDetails renames the instance and replaces its description.
+// ErrInstanceNameTaken is returned if the user has another instance with the same name.
+func (r *InstanceRepository) UpdateInstanceDetails(instance *instance_models.Instance, name string, description string) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkInstanceName(tx, instance.UserID, name, instance.ID); err != nil {
+			return err
+		}
+
+		return tx.Model(instance).Updates(map[string]interface{}{
+			"name":        name,
+			"description": description,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	instance.Name = name
+	instance.Description = description
+	return nil
+}
+
+// SetInstanceTags replaces the tags of the instance
+func (r *InstanceRepository) SetInstanceTags(instance *instance_models.Instance, tags []string) error {
+	instanceTags := make([]instance_models.InstanceTag, 0, len(tags))
+	for _, tag := range tags {
+		instanceTags = append(instanceTags, instance_models.InstanceTag{InstanceID: instance.ID, Tag: tag})
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance_id = ?", instance.ID).Delete(&instance_models.InstanceTag{}).Error; err != nil {
+			return err
+		}
+
+		if len(instanceTags) == 0 {
+			return nil
+		}
+		return tx.Create(&instanceTags).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	instance.Tags = instanceTags
+	return nil
+}
+
+// checkInstanceName returns ErrInstanceNameTaken if another instance of the user has the name.
+// Unnamed instances never conflict.
+func checkInstanceName(tx *gorm.DB, userID uint, name string, instanceID uint) error {
+	if name == "" {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&instance_models.Instance{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, instanceID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrInstanceNameTaken
+	}
+	return nil
+}
+
+func (r *InstanceRepository) GetInstanceEnv(instanceID uint) (map[string]string, error) {
+	var envs []instance_models.InstanceEnv
+	if err := r.DB.Where("instance_id = ?", instanceID).Find(&envs).Error; err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string, len(envs))
+	for _, e := range envs {
+		env[e.Key] = e.Value
+	}
+	return env, nil
+}
+
+// ReplaceInstanceEnv replaces every env value of the instance
+func (r *InstanceRepository) ReplaceInstanceEnv(instanceID uint, env map[string]string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceEnv{}).Error; err != nil {
+			return err
+		}
+
+		if len(env) == 0 {
+			return nil
+		}
+
+		envs := make([]instance_models.InstanceEnv, 0, len(env))
+		for key, value := range env {
+			envs = append(envs, instance_models.InstanceEnv{InstanceID: instanceID, Key: key, Value: value})
+		}
+		return tx.Create(&envs).Error
+	})
 }
@@ -0,0 +1,54 @@
+package instance_repositories
+
+import (
+	"errors"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordInstanceActivity stores a heartbeat as the latest activity of the instance.
+// The idle start is carried over from the previous heartbeat while no players are online.
+func (r *InstanceRepository) RecordInstanceActivity(activity *instance_models.InstanceActivity) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var previous instance_models.InstanceActivity
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&previous, activity.InstanceID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		switch {
+		case activity.PlayerCount > 0:
+			activity.IdleSince = nil
+		case err == nil && previous.IdleSince != nil:
+			activity.IdleSince = previous.IdleSince
+		default:
+			idleSince := activity.ReportedAt
+			activity.IdleSince = &idleSince
+		}
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(activity).Error
+	})
+}
+
+func (r *InstanceRepository) GetInstanceActivity(instanceID uint) (*instance_models.InstanceActivity, error) {
+	var activity instance_models.InstanceActivity
+	err := r.DB.First(&activity, instanceID).Error
+	return &activity, err
+}
+
+// ResetInstanceActivity forgets the reported activity so a new run starts its idle time from scratch
+func (r *InstanceRepository) ResetInstanceActivity(instanceID uint) error {
+	return r.DB.Where("instance_id = ?", instanceID).Delete(&instance_models.InstanceActivity{}).Error
+}
+
+// SetIdleStopMinutes changes the idle policy of the instance
+func (r *InstanceRepository) SetIdleStopMinutes(instance *instance_models.Instance, minutes int) error {
+	if err := r.DB.Model(instance).Update("idle_stop_minutes", minutes).Error; err != nil {
+		return err
+	}
+
+	instance.IdleStopMinutes = minutes
+	return nil
+}
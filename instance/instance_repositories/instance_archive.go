@@ -0,0 +1,40 @@
+package instance_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// SetInstanceArchive records the backup holding the disk of an archived instance, nil once it is not needed anymore
+func (r *InstanceRepository) SetInstanceArchive(instance *instance_models.Instance, backupID *uint) error {
+	if err := r.DB.Model(instance).Update("archive_backup_id", backupID).Error; err != nil {
+		return err
+	}
+
+	instance.ArchiveBackupID = backupID
+	return nil
+}
+
+func (r *InstanceRepository) GetInstancesByStatus(status instance_models.InstanceStatus) (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	err := r.DB.Where("status = ?", status).Find(&instances).Error
+	return &instances, err
+}
+
+// GetUnarchivedInstances returns the running instances still holding on to the backup they were archived in
+func (r *InstanceRepository) GetUnarchivedInstances() (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	err := r.DB.Where("status = ? AND archive_backup_id IS NOT NULL", instance_models.InstanceStatusRunning).Find(&instances).Error
+	return &instances, err
+}
+
+// GetInstancesStoppedSince returns the instances whose status has not changed since they stopped before before
+func (r *InstanceRepository) GetInstancesStoppedSince(before time.Time) (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	err := r.DB.
+		Where("status = ?", instance_models.InstanceStatusStopped).
+		Where("(SELECT MAX(created_at) FROM instance_status_changes WHERE instance_status_changes.instance_id = instances.id) < ?", before).
+		Find(&instances).Error
+	return &instances, err
+}
@@ -0,0 +1,107 @@
+package instance_repositories
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"gorm.io/gorm"
+)
+
+type InstanceCredentialsRepository struct {
+	DB *gorm.DB
+}
+
+func NewInstanceCredentialsRepository(db *gorm.DB) *InstanceCredentialsRepository {
+	return &InstanceCredentialsRepository{DB: db}
+}
+
+// IssueInstanceCredential revokes any active credential of the instance and issues a new one.
+// The returned plain secret is not stored and cannot be retrieved again.
+func (r *InstanceCredentialsRepository) IssueInstanceCredential(instanceID uint) (string, error) {
+	return r.issueInstanceCredential(instanceID, 0)
+}
+
+// RotateInstanceCredential issues a new credential while the agent keeps using keepID, the
+// credential it authenticated with. Every other active credential is revoked, e.g. one issued
+// by an earlier rotation that was never used. keepID stays valid until the new credential
+// is used for the first time, so an agent that failed to save the new secret is not locked out.
+func (r *InstanceCredentialsRepository) RotateInstanceCredential(instanceID uint, keepID uint) (string, error) {
+	return r.issueInstanceCredential(instanceID, keepID)
+}
+
+// issueInstanceCredential revokes the active credentials of the instance except keepID and issues a new one
+func (r *InstanceCredentialsRepository) issueInstanceCredential(instanceID uint, keepID uint) (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(secretBytes)
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&instance_models.InstanceCredential{}).
+			Where("instance_id = ? AND id <> ? AND revoked_at IS NULL", instanceID, keepID).
+			Update("revoked_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(&instance_models.InstanceCredential{
+			InstanceID: instanceID,
+			SecretHash: hashSecret(secret),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// VerifyInstanceCredential returns the active credential of the instance matching secret, nil if none does.
+// The first use of a rotated credential revokes the older ones, the agent evidently has the new secret.
+func (r *InstanceCredentialsRepository) VerifyInstanceCredential(instanceID uint, secret string) (*instance_models.InstanceCredential, error) {
+	var credentials []instance_models.InstanceCredential
+	if err := r.DB.Where("instance_id = ? AND revoked_at IS NULL", instanceID).Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+
+	hash := []byte(hashSecret(secret))
+	for i := range credentials {
+		credential := &credentials[i]
+		if subtle.ConstantTimeCompare(hash, []byte(credential.SecretHash)) != 1 {
+			continue
+		}
+
+		if len(credentials) > 1 {
+			err := r.DB.Model(&instance_models.InstanceCredential{}).
+				Where("instance_id = ? AND id < ? AND revoked_at IS NULL", instanceID, credential.ID).
+				Update("revoked_at", time.Now()).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+		return credential, nil
+	}
+
+	return nil, nil
+}
+
+// RevokeInstanceCredentials revokes all active credentials of the instance
+func (r *InstanceCredentialsRepository) RevokeInstanceCredentials(instanceID uint) error {
+	return revokeInstanceCredentials(r.DB, instanceID)
+}
+
+func revokeInstanceCredentials(db *gorm.DB, instanceID uint) error {
+	return db.Model(&instance_models.InstanceCredential{}).
+		Where("instance_id = ? AND revoked_at IS NULL", instanceID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
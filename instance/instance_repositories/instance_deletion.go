@@ -0,0 +1,90 @@
+package instance_repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// ScheduleInstanceDeletion moves the instance to pending deletion until deleteAt.
+// Returns ErrIllegalTransition when the instance cannot be deleted in its current status.
+func (r *InstanceRepository) ScheduleInstanceDeletion(instance *instance_models.Instance, deleteAt time.Time, finalBackup bool, actor string, reason string) error {
+	if !instance_models.CanTransition(instance.Status, instance_models.InstanceStatusPendingDeletion) {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, instance.Status, instance_models.InstanceStatusPendingDeletion)
+	}
+
+	// The deletion job only picks up pending deletions, so the schedule is harmless until the transition
+	if err := r.setInstanceDeletion(instance, &deleteAt, finalBackup); err != nil {
+		return err
+	}
+
+	if err := r.TransitionInstance(instance, instance_models.InstanceStatusPendingDeletion, actor, reason); err != nil {
+		r.setInstanceDeletion(instance, nil, false)
+		return err
+	}
+
+	return nil
+}
+
+// CancelInstanceDeletion moves an instance pending deletion back to stopped, or archived if it
+// has no provider instance. Returns ErrIllegalTransition when no deletion is pending.
+func (r *InstanceRepository) CancelInstanceDeletion(instance *instance_models.Instance, actor string) error {
+	// Running and other instances may move to stopped as well
+	if instance.Status != instance_models.InstanceStatusPendingDeletion {
+		return fmt.Errorf("%w: no deletion pending", ErrIllegalTransition)
+	}
+
+	to := instance_models.InstanceStatusStopped
+	if instance.RealID == "" && instance.ArchiveBackupID != nil {
+		to = instance_models.InstanceStatusArchived
+	}
+
+	if err := r.TransitionInstance(instance, to, actor, "deletion cancelled"); err != nil {
+		return err
+	}
+
+	return r.setInstanceDeletion(instance, nil, false)
+}
+
+// GetInstancesDueForDeletion returns the instances pending deletion whose grace period ended before now,
+// along with the deletions that failed after the provider terminated the instance
+func (r *InstanceRepository) GetInstancesDueForDeletion(now time.Time) (*[]instance_models.Instance, error) {
+	statuses := []instance_models.InstanceStatus{
+		instance_models.InstanceStatusPendingDeletion,
+		instance_models.InstanceStatusTerminating,
+		instance_models.InstanceStatusTerminated,
+	}
+
+	var instances []instance_models.Instance
+	err := r.DB.
+		Where("status IN ? AND deletion_scheduled_at <= ?", statuses, now).
+		Find(&instances).Error
+	return &instances, err
+}
+
+// SetInstanceFinalBackup records the final backup taken for the pending deletion
+func (r *InstanceRepository) SetInstanceFinalBackup(instance *instance_models.Instance, backupID uint) error {
+	if err := r.DB.Model(instance).UpdateColumn("final_backup_id", backupID).Error; err != nil {
+		return err
+	}
+
+	instance.FinalBackupID = &backupID
+	return nil
+}
+
+func (r *InstanceRepository) setInstanceDeletion(instance *instance_models.Instance, deleteAt *time.Time, finalBackup bool) error {
+	err := r.DB.Model(instance).Updates(map[string]interface{}{
+		"deletion_scheduled_at": deleteAt,
+		"final_backup":          finalBackup,
+		"final_backup_id":       nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	instance.DeletionScheduledAt = deleteAt
+	instance.FinalBackup = finalBackup
+	instance.FinalBackupID = nil
+	return nil
+}
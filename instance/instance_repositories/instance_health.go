@@ -0,0 +1,56 @@
+package instance_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// RecordInstanceHeartbeat stores the time of the latest contact from the agent
+func (r *InstanceRepository) RecordInstanceHeartbeat(instance *instance_models.Instance, at time.Time) error {
+	if err := r.DB.Model(instance).UpdateColumn("last_heartbeat_at", at).Error; err != nil {
+		return err
+	}
+
+	instance.LastHeartbeatAt = &at
+	return nil
+}
+
+// SetInstanceHealth changes the health of the instance and publishes the change to the owner.
+// Only running instances have a health, a late heartbeat cannot mark a stopped instance.
+// It reports whether the health was changed.
+func (r *InstanceRepository) SetInstanceHealth(instance *instance_models.Instance, health instance_models.InstanceHealth, reason string) (bool, error) {
+	result := r.DB.Model(&instance_models.Instance{}).
+		Where("id = ? AND status = ? AND health <> ?", instance.ID, instance_models.InstanceStatusRunning, health).
+		UpdateColumn("health", health)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	instance.Health = health
+
+	r.Events.Publish(instance.UserID, instance.ID, instance_events.EventHealth, instance_events.HealthData{
+		Health: string(health),
+		Reason: reason,
+	})
+	return true, nil
+}
+
+// GetSilentInstances returns the running instances not yet marked unhealthy whose agent
+// has not been heard from since before
+func (r *InstanceRepository) GetSilentInstances(before time.Time) (*[]instance_models.Instance, error) {
+	var instances []instance_models.Instance
+	err := r.DB.
+		Where("status = ? AND health <> ?", instance_models.InstanceStatusRunning, instance_models.InstanceHealthUnhealthy).
+		Where("last_heartbeat_at IS NULL OR last_heartbeat_at < ?", before).
+		Find(&instances).Error
+	if err != nil {
+		return nil, err
+	}
+	return &instances, nil
+}
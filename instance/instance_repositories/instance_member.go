@@ -0,0 +1,261 @@
+package instance_repositories
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/user/user_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientRole    = errors.New("instance role does not allow this")
+	ErrInviteInvalid       = errors.New("invite is invalid, expired or already used")
+	ErrInviteEmailMismatch = errors.New("invite was sent to another email")
+	ErrInviteOwner         = errors.New("the owner cannot join their own instance")
+)
+
+// InstanceMemberInfo is a user with access to an instance
+type InstanceMemberInfo struct {
+	UserID    uint                         `json:"userId"`
+	Email     string                       `json:"email"`
+	Role      instance_models.InstanceRole `json:"role"`
+	CreatedAt time.Time                    `json:"createdAt"`
+}
+
+// SharedInstance is an instance of another user the member has access to
+type SharedInstance struct {
+	Instance instance_models.Instance     `json:"instance"`
+	Role     instance_models.InstanceRole `json:"role"`
+}
+
+// GetMemberInstance returns the instance if the user owns it or is a member with at least the role,
+// together with the role of the user. gorm.ErrRecordNotFound is returned when the user has no access
+// and ErrInsufficientRole when their role is too low.
+func (r *InstanceRepository) GetMemberInstance(userEmail string, instanceID uint, role instance_models.InstanceRole) (*instance_models.Instance, instance_models.InstanceRole, error) {
+	var user user_models.User
+	if err := r.DB.Where("email = ?", userEmail).First(&user).Error; err != nil {
+		return nil, "", err
+	}
+
+	var instance instance_models.Instance
+	if err := r.DB.Preload("Tags").First(&instance, instanceID).Error; err != nil {
+		return nil, "", err
+	}
+
+	memberRole := instance_models.InstanceRoleOwner
+	if instance.UserID != user.ID {
+		var member instance_models.InstanceMember
+		if err := r.DB.Where("instance_id = ? AND user_id = ?", instance.ID, user.ID).First(&member).Error; err != nil {
+			return nil, "", err
+		}
+		memberRole = member.Role
+	}
+
+	if !memberRole.Includes(role) {
+		return nil, memberRole, ErrInsufficientRole
+	}
+	return &instance, memberRole, nil
+}
+
+type InstanceMemberRepository struct {
+	DB *gorm.DB
+}
+
+func NewInstanceMemberRepository(db *gorm.DB) *InstanceMemberRepository {
+	return &InstanceMemberRepository{DB: db}
+}
+
+// GetInstanceMembers returns the owner followed by the members of the instance
+func (r *InstanceMemberRepository) GetInstanceMembers(instance *instance_models.Instance) (*[]InstanceMemberInfo, error) {
+	var owner user_models.User
+	if err := r.DB.First(&owner, instance.UserID).Error; err != nil {
+		return nil, err
+	}
+
+	members := []InstanceMemberInfo{{
+		UserID:    owner.ID,
+		Email:     owner.Email,
+		Role:      instance_models.InstanceRoleOwner,
+		CreatedAt: instance.CreatedAt,
+	}}
+
+	var others []InstanceMemberInfo
+	err := r.DB.Model(&instance_models.InstanceMember{}).
+		Select("instance_members.user_id, users.email, instance_members.role, instance_members.created_at").
+		Joins("JOIN users ON users.id = instance_members.user_id").
+		Where("instance_members.instance_id = ?", instance.ID).
+		Order("instance_members.created_at").
+		Scan(&others).Error
+	if err != nil {
+		return nil, err
+	}
+
+	members = append(members, others...)
+	return &members, nil
+}
+
+// GetMemberUserIDs returns the users that are members of the instance, without the owner
+func (r *InstanceMemberRepository) GetMemberUserIDs(instanceID uint) ([]uint, error) {
+	var userIDs []uint
+	err := r.DB.Model(&instance_models.InstanceMember{}).Where("instance_id = ?", instanceID).Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// GetSharedInstances returns the instances of other users the user is a member of
+func (r *InstanceMemberRepository) GetSharedInstances(userID uint) (*[]SharedInstance, error) {
+	var members []instance_models.InstanceMember
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	roles := make(map[uint]instance_models.InstanceRole, len(members))
+	instanceIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		roles[member.InstanceID] = member.Role
+		instanceIDs = append(instanceIDs, member.InstanceID)
+	}
+
+	shared := []SharedInstance{}
+	if len(instanceIDs) == 0 {
+		return &shared, nil
+	}
+
+	var instances []instance_models.Instance
+	if err := r.DB.Preload("Tags").Where("id IN ?", instanceIDs).Order("created_at desc").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+
+	for _, instance := range instances {
+		shared = append(shared, SharedInstance{Instance: instance, Role: roles[instance.ID]})
+	}
+	return &shared, nil
+}
+
+// RemoveInstanceMember revokes the access of the user to the instance
+func (r *InstanceMemberRepository) RemoveInstanceMember(instanceID uint, userID uint) error {
+	result := r.DB.Where("instance_id = ? AND user_id = ?", instanceID, userID).Delete(&instance_models.InstanceMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CreateInstanceInvite stores the invite and returns the token of its link, earlier pending
+// invites of the same email to the instance are revoked. The token is not stored and cannot
+// be retrieved again.
+func (r *InstanceMemberRepository) CreateInstanceInvite(invite *instance_models.InstanceInvite) (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	invite.Email = strings.ToLower(invite.Email)
+	invite.TokenHash = hashSecret(token)
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&instance_models.InstanceInvite{}).
+			Where("instance_id = ? AND email = ? AND accepted_at IS NULL AND revoked_at IS NULL", invite.InstanceID, invite.Email).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(invite).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetPendingInstanceInvites returns the invites of the instance that can still be accepted
+func (r *InstanceMemberRepository) GetPendingInstanceInvites(instanceID uint) (*[]instance_models.InstanceInvite, error) {
+	var invites []instance_models.InstanceInvite
+	err := r.DB.
+		Where("instance_id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", instanceID, time.Now()).
+		Order("created_at desc").
+		Find(&invites).Error
+	return &invites, err
+}
+
+// RevokeInstanceInvite revokes a pending invite of the instance
+func (r *InstanceMemberRepository) RevokeInstanceInvite(instanceID uint, inviteID uint) error {
+	result := r.DB.Model(&instance_models.InstanceInvite{}).
+		Where("id = ? AND instance_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", inviteID, instanceID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AcceptInstanceInvite makes the user a member of the invite's instance with its role. A user
+// who already is a member gets the role of the invite, which is how the owner changes roles.
+func (r *InstanceMemberRepository) AcceptInstanceInvite(token string, user *user_models.User) (*instance_models.InstanceMember, error) {
+	var member instance_models.InstanceMember
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var invite instance_models.InstanceInvite
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("token_hash = ?", hashSecret(token)).First(&invite).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if invite.AcceptedAt != nil || invite.RevokedAt != nil || now.After(invite.ExpiresAt) {
+			return ErrInviteInvalid
+		}
+		if !strings.EqualFold(invite.Email, user.Email) {
+			return ErrInviteEmailMismatch
+		}
+
+		// The instance may have been terminated since
+		var instance instance_models.Instance
+		err = tx.First(&instance, invite.InstanceID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInviteInvalid
+		}
+		if err != nil {
+			return err
+		}
+		if instance.UserID == user.ID {
+			return ErrInviteOwner
+		}
+
+		member = instance_models.InstanceMember{
+			InstanceID: invite.InstanceID,
+			UserID:     user.ID,
+			Role:       invite.Role,
+			InvitedBy:  invite.InvitedBy,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "instance_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role", "invited_by"}),
+		}).Create(&member).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&invite).Update("accepted_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
@@ -0,0 +1,68 @@
+package instance_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+)
+
+// UpdateInstanceMeteredAt moves the start of the unbilled running time to meteredAt, nil to stop
+// billing. It only applies if nobody changed it since the instance was read and reports whether it did,
+// so a stale copy cannot restart billing of an instance the shutdown already stopped billing.
+func (r *InstanceRepository) UpdateInstanceMeteredAt(instance *instance_models.Instance, meteredAt *time.Time) (bool, error) {
+	query := r.DB.Model(&instance_models.Instance{}).Where("id = ?", instance.ID)
+	if instance.MeteredAt == nil {
+		query = query.Where("metered_at IS NULL")
+	} else {
+		query = query.Where("metered_at = ?", *instance.MeteredAt)
+	}
+
+	result := query.UpdateColumn("metered_at", meteredAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	instance.MeteredAt = meteredAt
+	return true, nil
+}
+
+// ClearInstanceMetering stops billing the instance whatever its metering state, the agent shutdown is authoritative
+func (r *InstanceRepository) ClearInstanceMetering(instance *instance_models.Instance) error {
+	if err := r.DB.Model(instance).UpdateColumn("metered_at", nil).Error; err != nil {
+		return err
+	}
+
+	instance.MeteredAt = nil
+	return nil
+}
+
+// SetCycleWarningMinutes records the lowest low-cycles warning threshold sent, 0 to warn again
+func (r *InstanceRepository) SetCycleWarningMinutes(instance *instance_models.Instance, minutes int) error {
+	if err := r.DB.Model(instance).UpdateColumn("cycle_warning_minutes", minutes).Error; err != nil {
+		return err
+	}
+
+	instance.CycleWarningMinutes = minutes
+	return nil
+}
+
+func (r *InstanceRepository) SetInstancePublicIP(instance *instance_models.Instance, publicIP string) error {
+	if err := r.DB.Model(instance).UpdateColumn("public_ip", publicIP).Error; err != nil {
+		return err
+	}
+
+	instance.PublicIP = publicIP
+	return nil
+}
+
+func (r *InstanceRepository) SetInstanceRealID(instance *instance_models.Instance, realID string) error {
+	if err := r.DB.Model(instance).UpdateColumn("real_id", realID).Error; err != nil {
+		return err
+	}
+
+	instance.RealID = realID
+	return nil
+}
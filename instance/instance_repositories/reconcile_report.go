@@ -0,0 +1,31 @@
+package instance_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"gorm.io/gorm"
+)
+
+type ReconcileReportRepository struct {
+	DB *gorm.DB
+}
+
+func NewReconcileReportRepository(db *gorm.DB) *ReconcileReportRepository {
+	return &ReconcileReportRepository{DB: db}
+}
+
+func (r *ReconcileReportRepository) CreateReconcileReport(report *instance_models.ReconcileReport) error {
+	return r.DB.Create(report).Error
+}
+
+func (r *ReconcileReportRepository) GetLatestReconcileReport() (*instance_models.ReconcileReport, error) {
+	var report instance_models.ReconcileReport
+	err := r.DB.Order("id DESC").First(&report).Error
+	return &report, err
+}
+
+// DeleteReconcileReportsBefore deletes the reports created before the given time
+func (r *ReconcileReportRepository) DeleteReconcileReportsBefore(before time.Time) error {
+	return r.DB.Where("created_at < ?", before).Delete(&instance_models.ReconcileReport{}).Error
+}
@@ -0,0 +1,87 @@
+package ledger_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetInstanceCycles returns the cycle balance the user's instance burns from, its wallet's if it has one.
+func GetInstanceCycles(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	balance, err := appCtx.LedgerRepository.GetBalance(instance_billing.FundingAccount(instance))
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get balance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":  balance,
+		"walletId": instance.WalletID,
+	})
+}
+
+// GetInstanceCyclesHistory returns a page of the ledger entries of the user's instance.
+func GetInstanceCyclesHistory(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleViewer)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	page, pageSize := utils.GetPagination(c)
+
+	entries, total, err := appCtx.LedgerRepository.GetHistory(ledger_repositories.InstanceAccount(instance.ID), page, pageSize)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get history", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
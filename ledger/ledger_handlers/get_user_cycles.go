@@ -0,0 +1,57 @@
+package ledger_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetUserCycles returns the cycle balance held by the user outside of instances.
+func GetUserCycles(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	balance, err := appCtx.LedgerRepository.GetBalance(ledger_repositories.UserAccount(user.ID))
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get balance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance": balance,
+	})
+}
+
+// GetUserCyclesHistory returns a page of the ledger entries of the user's account.
+func GetUserCyclesHistory(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	page, pageSize := utils.GetPagination(c)
+
+	entries, total, err := appCtx.LedgerRepository.GetHistory(ledger_repositories.UserAccount(user.ID), page, pageSize)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get history", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
@@ -0,0 +1,66 @@
+package ledger_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for granting cycles. Exactly one of UserID and InstanceID must be set.
+type GrantCyclesRequestBody struct {
+	UserID      uint   `json:"userId"`
+	InstanceID  uint   `json:"instanceId"`
+	Amount      uint   `json:"amount" binding:"required"`
+	Description string `json:"description" binding:"required"`
+}
+
+// GrantCycles credits cycles to a user or an instance on behalf of an admin.
+func GrantCycles(c *gin.Context, appCtx *app.Context) {
+	var request GrantCyclesRequestBody
+
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	userEmail := c.GetString("userEmail")
+
+	var account ledger_repositories.AccountRef
+	switch {
+	case request.UserID != 0 && request.InstanceID == 0:
+		if _, err := appCtx.UserRepository.GetUser(request.UserID); err != nil {
+			utils.HandleError(c, http.StatusNotFound, "User not found", err, userEmail)
+			return
+		}
+		account = ledger_repositories.UserAccount(request.UserID)
+	case request.InstanceID != 0 && request.UserID == 0:
+		if _, err := appCtx.InstanceRepository.GetInstance(request.InstanceID); err != nil {
+			utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+			return
+		}
+		account = ledger_repositories.InstanceAccount(request.InstanceID)
+	default:
+		utils.HandleError(c, http.StatusBadRequest, "Either userId or instanceId is required", errors.New("invalid grant target"), userEmail)
+		return
+	}
+
+	transaction, err := appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+		From:        ledger_repositories.SystemAccount(ledger_models.SystemAccountGrants),
+		To:          account,
+		Amount:      request.Amount,
+		Reason:      ledger_models.ReasonAdminGrant,
+		Description: request.Description,
+		Actor:       userEmail,
+	})
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to grant cycles", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
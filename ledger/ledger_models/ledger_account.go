@@ -0,0 +1,33 @@
+package ledger_models
+
+import (
+	"time"
+)
+
+type AccountType string
+
+const (
+	AccountTypeUser     AccountType = "user"
+	AccountTypeInstance AccountType = "instance"
+	AccountTypeWallet   AccountType = "wallet"
+	AccountTypeSystem   AccountType = "system"
+)
+
+// System accounts are the counterparties of cycles entering or leaving the platform
+const (
+	SystemAccountPurchases = "purchases" // source of purchased cycles
+	SystemAccountGrants    = "grants"    // source of cycles granted by admins
+	SystemAccountBurned    = "burned"    // sink of burned cycles
+)
+
+// LedgerAccount holds the cycle balance of a user, an instance, a wallet or the system.
+// The balance is only changed together with the entries explaining the change.
+type LedgerAccount struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Type      AccountType `gorm:"not null;uniqueIndex:idx_ledger_account_owner" json:"type"`
+	OwnerID   uint        `gorm:"not null;uniqueIndex:idx_ledger_account_owner" json:"ownerId"` // User, instance or wallet ID, 0 for system accounts
+	Name      string      `gorm:"not null;default:'';uniqueIndex:idx_ledger_account_owner" json:"name"`
+	Balance   int64       `gorm:"not null;default:0" json:"balance"`
+}
@@ -0,0 +1,17 @@
+package ledger_models
+
+import (
+	"time"
+)
+
+// LedgerEntry is one side of a ledger transaction.
+// Amount is positive when the account is credited and negative when it is debited.
+type LedgerEntry struct {
+	ID            uint               `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	TransactionID uint               `gorm:"not null;index" json:"transactionId"`
+	AccountID     uint               `gorm:"not null;index" json:"accountId"`
+	Amount        int64              `gorm:"not null" json:"amount"`
+	BalanceAfter  int64              `gorm:"not null" json:"balanceAfter"`
+	Transaction   *LedgerTransaction `gorm:"foreignKey:TransactionID" json:"transaction,omitempty"`
+}
@@ -0,0 +1,43 @@
+package ledger_models
+
+import (
+	"time"
+)
+
+type TransactionReason string
+
+const (
+	ReasonPurchase          TransactionReason = "purchase"
+	ReasonBurn              TransactionReason = "burn"
+	ReasonRefund            TransactionReason = "refund"
+	ReasonAdminGrant        TransactionReason = "admin_grant"
+	ReasonFailedStartupBurn TransactionReason = "failed_startup_burn"
+	ReasonContribution      TransactionReason = "contribution"   // A user moved cycles into a wallet
+	ReasonBackupStorage     TransactionReason = "backup_storage" // Storage of instance backups
+	ReasonTermination       TransactionReason = "termination"    // Balance of a terminated instance returned to its owner
+)
+
+// LedgerTransaction groups the balanced entries of a single cycle movement.
+// Transactions and their entries are never updated or deleted.
+type LedgerTransaction struct {
+	ID             uint              `gorm:"primaryKey" json:"id"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	Reason         TransactionReason `gorm:"not null;index" json:"reason"`
+	IdempotencyKey *string           `gorm:"uniqueIndex" json:"-"`
+	Description    string            `json:"description"`
+	Actor          string            `json:"actor"`                             // Email of the user or name of the job that caused it
+	UserID         *uint             `gorm:"index" json:"userId,omitempty"`     // User the movement is attributed to, e.g. the contributor
+	InstanceID     *uint             `gorm:"index" json:"instanceId,omitempty"` // Instance the movement is attributed to, e.g. the one burning
+	Entries        []LedgerEntry     `gorm:"foreignKey:TransactionID" json:"entries,omitempty"`
+}
+
+// Credited returns the amount of the entry that received the cycles. Entries are not
+// ordered, a replayed transaction may load them in any order.
+func (t *LedgerTransaction) Credited() int64 {
+	for _, entry := range t.Entries {
+		if entry.Amount > 0 {
+			return entry.Amount
+		}
+	}
+	return 0
+}
@@ -0,0 +1,350 @@
+package ledger_repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient balance")
+
+// AccountRef identifies a ledger account, which is created on first use
+type AccountRef struct {
+	Type    ledger_models.AccountType
+	OwnerID uint
+	Name    string
+}
+
+func UserAccount(userID uint) AccountRef {
+	return AccountRef{Type: ledger_models.AccountTypeUser, OwnerID: userID}
+}
+
+func InstanceAccount(instanceID uint) AccountRef {
+	return AccountRef{Type: ledger_models.AccountTypeInstance, OwnerID: instanceID}
+}
+
+func WalletAccount(walletID uint) AccountRef {
+	return AccountRef{Type: ledger_models.AccountTypeWallet, OwnerID: walletID}
+}
+
+func SystemAccount(name string) AccountRef {
+	return AccountRef{Type: ledger_models.AccountTypeSystem, Name: name}
+}
+
+// Transfer describes a movement of cycles between two accounts
+type Transfer struct {
+	From        AccountRef
+	To          AccountRef
+	Amount      uint
+	Reason      ledger_models.TransactionReason
+	Description string
+	Actor       string
+
+	// UserID and InstanceID attribute the transfer to a user or an instance, 0 for none
+	UserID     uint
+	InstanceID uint
+
+	// IdempotencyKey makes repeated transfers with the same key return the first transaction
+	IdempotencyKey string
+
+	// ClampToBalance moves at most the available balance of From instead of failing
+	ClampToBalance bool
+}
+
+type LedgerRepository struct {
+	DB *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) *LedgerRepository {
+	return &LedgerRepository{DB: db}
+}
+
+// Transfer posts a transfer in its own database transaction
+func (r *LedgerRepository) Transfer(transfer Transfer) (*ledger_models.LedgerTransaction, error) {
+	var result *ledger_models.LedgerTransaction
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = r.TransferTx(tx, transfer)
+		return err
+	})
+	return result, err
+}
+
+// TransferTx posts a transfer within tx. Both accounts are locked for the duration of tx.
+// Only system accounts may go negative. When ClampToBalance leaves nothing to move,
+// no transaction is recorded and nil is returned.
+func (r *LedgerRepository) TransferTx(tx *gorm.DB, transfer Transfer) (*ledger_models.LedgerTransaction, error) {
+	if transfer.Amount == 0 {
+		return nil, errors.New("transfer amount must be positive")
+	}
+
+	if transfer.From == transfer.To {
+		return nil, errors.New("cannot transfer to the same account")
+	}
+
+	// Repeated requests return the transaction that was already posted
+	if transfer.IdempotencyKey != "" {
+		var existing ledger_models.LedgerTransaction
+		err := tx.Preload("Entries").Where("idempotency_key = ?", transfer.IdempotencyKey).First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+
+	from, to, err := lockAccounts(tx, transfer.From, transfer.To)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := int64(transfer.Amount)
+	if from.Type != ledger_models.AccountTypeSystem && from.Balance < amount {
+		if !transfer.ClampToBalance {
+			return nil, ErrInsufficientBalance
+		}
+		amount = max(from.Balance, 0)
+	}
+
+	if amount == 0 {
+		return nil, nil
+	}
+
+	from.Balance -= amount
+	to.Balance += amount
+
+	for _, account := range []*ledger_models.LedgerAccount{from, to} {
+		if err := tx.Model(account).Update("balance", account.Balance).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	transaction := ledger_models.LedgerTransaction{
+		Reason:      transfer.Reason,
+		Description: transfer.Description,
+		Actor:       transfer.Actor,
+		Entries: []ledger_models.LedgerEntry{
+			{AccountID: from.ID, Amount: -amount, BalanceAfter: from.Balance},
+			{AccountID: to.ID, Amount: amount, BalanceAfter: to.Balance},
+		},
+	}
+	if transfer.IdempotencyKey != "" {
+		transaction.IdempotencyKey = &transfer.IdempotencyKey
+	}
+	if transfer.UserID != 0 {
+		transaction.UserID = &transfer.UserID
+	}
+	if transfer.InstanceID != 0 {
+		transaction.InstanceID = &transfer.InstanceID
+	}
+
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// GetBalance returns the balance of an account, 0 if it was never used
+func (r *LedgerRepository) GetBalance(ref AccountRef) (int64, error) {
+	var account ledger_models.LedgerAccount
+	err := r.DB.Where(accountWhere(ref)).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	return account.Balance, err
+}
+
+// GetHistory returns a page of the account entries, newest first, and the total number of entries
+func (r *LedgerRepository) GetHistory(ref AccountRef, page int, pageSize int) (*[]ledger_models.LedgerEntry, int64, error) {
+	entries := []ledger_models.LedgerEntry{}
+
+	var account ledger_models.LedgerAccount
+	err := r.DB.Where(accountWhere(ref)).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &entries, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := r.DB.Model(&ledger_models.LedgerEntry{}).Where("account_id = ?", account.ID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err = r.DB.Preload("Transaction").
+		Where("account_id = ?", account.ID).
+		Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&entries).Error
+
+	return &entries, total, err
+}
+
+// GetCreditsByUser sums the credits of the account for the reasons by the user they are attributed to
+func (r *LedgerRepository) GetCreditsByUser(ref AccountRef, reasons ...ledger_models.TransactionReason) (map[uint]int64, error) {
+	return r.sumByUser(ref, "ledger_entries.amount > 0", reasons)
+}
+
+// GetDebitsByUser sums the debits of the account for the reasons by the user they are attributed to.
+// The sums are positive.
+func (r *LedgerRepository) GetDebitsByUser(ref AccountRef, reasons ...ledger_models.TransactionReason) (map[uint]int64, error) {
+	totals, err := r.sumByUser(ref, "ledger_entries.amount < 0", reasons)
+	for userID, amount := range totals {
+		totals[userID] = -amount
+	}
+	return totals, err
+}
+
+func (r *LedgerRepository) sumByUser(ref AccountRef, condition string, reasons []ledger_models.TransactionReason) (map[uint]int64, error) {
+	totals := make(map[uint]int64)
+
+	var account ledger_models.LedgerAccount
+	err := r.DB.Where(accountWhere(ref)).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return totals, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		UserID uint
+		Amount int64
+	}
+	err = r.DB.Model(&ledger_models.LedgerEntry{}).
+		Select("ledger_transactions.user_id, SUM(ledger_entries.amount) AS amount").
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_entries.transaction_id").
+		Where("ledger_entries.account_id = ? AND ledger_transactions.reason IN ? AND ledger_transactions.user_id IS NOT NULL", account.ID, reasons).
+		Where(condition).
+		Group("ledger_transactions.user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		totals[row.UserID] = row.Amount
+	}
+	return totals, nil
+}
+
+// ImportLegacyCycles moves rows of the former instance_cycles and instance_burned_cycles
+// tables into the ledger. Every row is imported once, so it is safe to run on each start.
+func (r *LedgerRepository) ImportLegacyCycles() error {
+	legacyTables := []struct {
+		table  string
+		reason ledger_models.TransactionReason
+	}{
+		{"instance_cycles", ledger_models.ReasonPurchase},
+		{"instance_burned_cycles", ledger_models.ReasonBurn},
+	}
+
+	for _, legacy := range legacyTables {
+		if !r.DB.Migrator().HasTable(legacy.table) {
+			continue
+		}
+
+		var rows []struct {
+			ID         uint
+			InstanceID uint
+			Amount     uint
+		}
+		if err := r.DB.Table(legacy.table).Where("deleted_at IS NULL AND amount > 0").Order("id").Find(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read %s: %v", legacy.table, err)
+		}
+
+		for _, row := range rows {
+			transfer := Transfer{
+				Amount:         row.Amount,
+				Reason:         legacy.reason,
+				Description:    fmt.Sprintf("Imported from %s #%d", legacy.table, row.ID),
+				Actor:          "legacy-import",
+				IdempotencyKey: fmt.Sprintf("legacy:%s:%d", legacy.table, row.ID),
+			}
+
+			if legacy.reason == ledger_models.ReasonBurn {
+				transfer.From = InstanceAccount(row.InstanceID)
+				transfer.To = SystemAccount(ledger_models.SystemAccountBurned)
+				transfer.ClampToBalance = true
+			} else {
+				transfer.From = SystemAccount(ledger_models.SystemAccountPurchases)
+				transfer.To = InstanceAccount(row.InstanceID)
+			}
+
+			if err := r.importLegacyRow(transfer); err != nil {
+				return fmt.Errorf("failed to import %s #%d: %v", legacy.table, row.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// importLegacyRow posts the transfer of a legacy row. A burn clamped to nothing records no
+// transfer, an empty transaction keeps its key so the row is not burned again on the next start.
+func (r *LedgerRepository) importLegacyRow(transfer Transfer) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		transaction, err := r.TransferTx(tx, transfer)
+		if err != nil || transaction != nil {
+			return err
+		}
+
+		return tx.Create(&ledger_models.LedgerTransaction{
+			Reason:         transfer.Reason,
+			Description:    transfer.Description + ", nothing left to burn",
+			Actor:          transfer.Actor,
+			IdempotencyKey: &transfer.IdempotencyKey,
+		}).Error
+	})
+}
+
+// lockAccounts creates the accounts if needed and locks them in ID order to avoid deadlocks
+func lockAccounts(tx *gorm.DB, fromRef AccountRef, toRef AccountRef) (*ledger_models.LedgerAccount, *ledger_models.LedgerAccount, error) {
+	ids := make([]uint, 0, 2)
+	for _, ref := range []AccountRef{fromRef, toRef} {
+		account := ledger_models.LedgerAccount{Type: ref.Type, OwnerID: ref.OwnerID, Name: ref.Name}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&account).Error; err != nil {
+			return nil, nil, err
+		}
+		if err := tx.Where(accountWhere(ref)).First(&account).Error; err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, account.ID)
+	}
+
+	var accounts []ledger_models.LedgerAccount
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&accounts).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var from, to *ledger_models.LedgerAccount
+	for i := range accounts {
+		switch accounts[i].ID {
+		case ids[0]:
+			from = &accounts[i]
+		case ids[1]:
+			to = &accounts[i]
+		}
+	}
+
+	if from == nil || to == nil {
+		return nil, nil, errors.New("failed to lock ledger accounts")
+	}
+
+	return from, to, nil
+}
+
+func accountWhere(ref AccountRef) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     ref.Type,
+		"owner_id": ref.OwnerID,
+		"name":     ref.Name,
+	}
+}
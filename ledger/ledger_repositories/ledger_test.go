@@ -0,0 +1,206 @@
+package ledger_repositories
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestLedger(t *testing.T) *LedgerRepository {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	// Every connection to :memory: is a separate database
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get database: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&ledger_models.LedgerAccount{}, &ledger_models.LedgerTransaction{}, &ledger_models.LedgerEntry{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return NewLedgerRepository(db)
+}
+
+func requireBalance(t *testing.T, ledger *LedgerRepository, ref AccountRef, want int64) {
+	t.Helper()
+
+	balance, err := ledger.GetBalance(ref)
+	if err != nil {
+		t.Fatalf("GetBalance: %v", err)
+	}
+	if balance != want {
+		t.Fatalf("balance of %s %d %q = %d, want %d", ref.Type, ref.OwnerID, ref.Name, balance, want)
+	}
+}
+
+func grant(t *testing.T, ledger *LedgerRepository, to AccountRef, amount uint) {
+	t.Helper()
+
+	_, err := ledger.Transfer(Transfer{
+		From:   SystemAccount(ledger_models.SystemAccountGrants),
+		To:     to,
+		Amount: amount,
+		Reason: ledger_models.ReasonAdminGrant,
+	})
+	if err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+}
+
+func TestTransferMovesBalance(t *testing.T) {
+	ledger := newTestLedger(t)
+	user := UserAccount(1)
+	instance := InstanceAccount(2)
+	grant(t, ledger, user, 100)
+
+	transaction, err := ledger.Transfer(Transfer{
+		From:   user,
+		To:     instance,
+		Amount: 40,
+		Reason: ledger_models.ReasonContribution,
+		UserID: 1,
+	})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	if len(transaction.Entries) != 2 {
+		t.Fatalf("entries = %d, want 2", len(transaction.Entries))
+	}
+	if transaction.Credited() != 40 {
+		t.Fatalf("credited = %d, want 40", transaction.Credited())
+	}
+
+	requireBalance(t, ledger, user, 60)
+	requireBalance(t, ledger, instance, 40)
+
+	// System accounts are the only ones allowed to go negative
+	requireBalance(t, ledger, SystemAccount(ledger_models.SystemAccountGrants), -100)
+}
+
+func TestTransferRejectsInvalidTransfers(t *testing.T) {
+	ledger := newTestLedger(t)
+	user := UserAccount(1)
+
+	if _, err := ledger.Transfer(Transfer{From: user, To: InstanceAccount(1), Amount: 0}); err == nil {
+		t.Fatal("transfer of 0 succeeded")
+	}
+
+	if _, err := ledger.Transfer(Transfer{From: user, To: user, Amount: 1}); err == nil {
+		t.Fatal("transfer to the same account succeeded")
+	}
+}
+
+func TestTransferInsufficientBalance(t *testing.T) {
+	ledger := newTestLedger(t)
+	user := UserAccount(1)
+	grant(t, ledger, user, 10)
+
+	_, err := ledger.Transfer(Transfer{From: user, To: InstanceAccount(1), Amount: 11})
+	if !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("err = %v, want ErrInsufficientBalance", err)
+	}
+
+	requireBalance(t, ledger, user, 10)
+	requireBalance(t, ledger, InstanceAccount(1), 0)
+}
+
+func TestTransferClampToBalance(t *testing.T) {
+	ledger := newTestLedger(t)
+	instance := InstanceAccount(1)
+	burned := SystemAccount(ledger_models.SystemAccountBurned)
+	grant(t, ledger, instance, 10)
+
+	transaction, err := ledger.Transfer(Transfer{From: instance, To: burned, Amount: 25, Reason: ledger_models.ReasonBurn, ClampToBalance: true})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if transaction.Credited() != 10 {
+		t.Fatalf("credited = %d, want 10", transaction.Credited())
+	}
+	requireBalance(t, ledger, instance, 0)
+
+	// Nothing left to move, no transaction is recorded
+	transaction, err = ledger.Transfer(Transfer{From: instance, To: burned, Amount: 5, Reason: ledger_models.ReasonBurn, ClampToBalance: true})
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if transaction != nil {
+		t.Fatalf("transaction = %d, want nil", transaction.ID)
+	}
+	requireBalance(t, ledger, burned, 10)
+}
+
+func TestTransferIdempotency(t *testing.T) {
+	ledger := newTestLedger(t)
+	user := UserAccount(1)
+	instance := InstanceAccount(1)
+	grant(t, ledger, user, 100)
+
+	transfer := Transfer{
+		From:           user,
+		To:             instance,
+		Amount:         30,
+		Reason:         ledger_models.ReasonContribution,
+		IdempotencyKey: "contribution:1",
+	}
+
+	first, err := ledger.Transfer(transfer)
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	// A replay with a different amount still returns the first transaction
+	transfer.Amount = 50
+	replayed, err := ledger.Transfer(transfer)
+	if err != nil {
+		t.Fatalf("replayed Transfer: %v", err)
+	}
+
+	if replayed.ID != first.ID {
+		t.Fatalf("replayed transaction = %d, want %d", replayed.ID, first.ID)
+	}
+	if len(replayed.Entries) != 2 || replayed.Credited() != 30 {
+		t.Fatalf("replayed entries = %d credited %d, want 2 credited 30", len(replayed.Entries), replayed.Credited())
+	}
+
+	requireBalance(t, ledger, user, 70)
+	requireBalance(t, ledger, instance, 30)
+}
+
+func TestImportLegacyCyclesKeepsClampedBurns(t *testing.T) {
+	ledger := newTestLedger(t)
+	instance := InstanceAccount(1)
+
+	err := ledger.DB.Exec("CREATE TABLE instance_burned_cycles (id INTEGER PRIMARY KEY, instance_id INTEGER, amount INTEGER, deleted_at DATETIME)").Error
+	if err == nil {
+		err = ledger.DB.Exec("INSERT INTO instance_burned_cycles (id, instance_id, amount) VALUES (1, 1, 20)").Error
+	}
+	if err != nil {
+		t.Fatalf("failed to create legacy table: %v", err)
+	}
+
+	// Nothing to burn from, the row must still count as imported
+	if err := ledger.ImportLegacyCycles(); err != nil {
+		t.Fatalf("ImportLegacyCycles: %v", err)
+	}
+
+	grant(t, ledger, instance, 50)
+	if err := ledger.ImportLegacyCycles(); err != nil {
+		t.Fatalf("ImportLegacyCycles: %v", err)
+	}
+
+	requireBalance(t, ledger, instance, 50)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/mooncorn/gshub-core/db"
@@ -11,15 +13,38 @@ import (
 	"github.com/mooncorn/gshub-main-api/app"
 	"gorm.io/gorm"
 
+	"github.com/mooncorn/gshub-main-api/backup/backup_models"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
 	"github.com/mooncorn/gshub-main-api/plan/plan_models"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_models"
 	"github.com/mooncorn/gshub-main-api/service/service_models"
 	"github.com/mooncorn/gshub-main-api/user/user_models"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_models"
 
+	"github.com/mooncorn/gshub-main-api/backup/backup_handlers"
+	"github.com/mooncorn/gshub-main-api/backup/backup_jobs"
 	"github.com/mooncorn/gshub-main-api/instance/instance_handlers"
+	"github.com/mooncorn/gshub-main-api/instance/instance_jobs"
+	"github.com/mooncorn/gshub-main-api/instance/instance_middlewares"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_handlers"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
 	"github.com/mooncorn/gshub-main-api/metadata/metadata_handlers"
+	"github.com/mooncorn/gshub-main-api/notification/notification_handlers"
+	"github.com/mooncorn/gshub-main-api/payment/payment_handlers"
+	"github.com/mooncorn/gshub-main-api/plan/plan_handlers"
+	"github.com/mooncorn/gshub-main-api/plan/plan_validation"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_handlers"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_jobs"
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
 	"github.com/mooncorn/gshub-main-api/service/service_handlers"
+	"github.com/mooncorn/gshub-main-api/service/service_lint"
+	"github.com/mooncorn/gshub-main-api/service/service_repositories"
 	"github.com/mooncorn/gshub-main-api/user/user_handlers"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_handlers"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -38,6 +63,25 @@ func main() {
 	// Create application context
 	appCtx := app.NewContext(gormDB)
 
+	// Plans the provider cannot launch and broken presets fail at instance creation, report them early
+	checkPlans(appCtx)
+	checkServicePresets(appCtx)
+
+	// Start background jobs
+	go instance_jobs.RunReconciler(appCtx,
+		utils.GetDurationEnv("RECONCILER_INTERVAL", 5*time.Minute),
+		utils.GetDurationEnv("RECONCILE_REPORT_RETENTION", 7*24*time.Hour))
+	go instance_jobs.RunMetering(appCtx, utils.GetDurationEnv("METERING_INTERVAL", time.Minute))
+	go instance_jobs.RunLivenessMonitor(appCtx,
+		utils.GetDurationEnv("LIVENESS_INTERVAL", time.Minute),
+		utils.GetDurationEnv("HEARTBEAT_TIMEOUT", 3*time.Minute))
+	go schedule_jobs.RunScheduler(appCtx, utils.GetDurationEnv("SCHEDULER_INTERVAL", 30*time.Second))
+	go instance_jobs.RunDeletions(appCtx, utils.GetDurationEnv("DELETION_INTERVAL", time.Minute))
+	go instance_jobs.RunArchiver(appCtx,
+		utils.GetDurationEnv("ARCHIVER_INTERVAL", 5*time.Minute),
+		utils.GetDurationEnv("ARCHIVE_AFTER", 0))
+	go backup_jobs.RunBackups(appCtx, utils.GetDurationEnv("BACKUP_INTERVAL", 5*time.Minute))
+
 	// Setup and start the main server
 	mainRouter := setupMainRouter(appCtx)
 	go startServer(mainRouter, ":8080")
@@ -59,12 +103,45 @@ func initializeDatabase() *gorm.DB {
 		&user_models.User{},
 		&plan_models.Plan{},
 		&service_models.Service{},
+		&service_models.ServicePreset{},
 		&instance_models.Instance{},
-		&instance_models.InstanceCycle{},
-		&instance_models.InstanceBurnedCycle{},
+		&instance_models.InstanceEnv{},
+		&instance_models.InstanceTag{},
+		&instance_models.InstanceStatusChange{},
+		&instance_models.InstancePlanChange{},
+		&instance_models.InstanceActivity{},
+		&instance_models.InstanceMember{},
+		&instance_models.InstanceInvite{},
+		&instance_models.InstanceCredential{},
+		&instance_models.ReconcileReport{},
+		&ledger_models.LedgerAccount{},
+		&ledger_models.LedgerTransaction{},
+		&ledger_models.LedgerEntry{},
+		&payment_models.Purchase{},
+		&notification_models.Notification{},
+		&schedule_models.InstanceSchedule{},
+		&wallet_models.Wallet{},
+		&wallet_models.WalletMember{},
+		&backup_models.InstanceBackup{},
+		&backup_models.InstanceBackupPolicy{},
 	); err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+
+	// Services without presets in the database start from the seed file
+	configs, err := service_presets.GetServiceConfigurations()
+	if err != nil {
+		log.Fatal("Failed to read service presets:", err)
+	}
+	if err := service_repositories.NewServiceRepository(gormDB.DB).SeedServicePresets(configs); err != nil {
+		log.Fatal("Failed to seed service presets:", err)
+	}
+
+	// Move balances recorded before the ledger existed
+	if err := ledger_repositories.NewLedgerRepository(gormDB.DB).ImportLegacyCycles(); err != nil {
+		log.Fatal("Failed to import legacy cycles:", err)
+	}
+
 	return gormDB.DB
 }
 
@@ -88,28 +165,98 @@ func setupMainRouter(appCtx *app.Context) *gin.Engine {
 	// Public routes
 	r.POST("/signin", appCtx.HandlerWrapper(user_handlers.SignIn))
 	r.GET("/metadata", appCtx.HandlerWrapper(metadata_handlers.GetMetadata))
+	r.POST("/payments/webhook", appCtx.HandlerWrapper(payment_handlers.PaymentWebhook))
 
 	r.Use(middlewares.RequireUser)
 
 	// Protected routes
 	r.GET("/user", appCtx.HandlerWrapper(user_handlers.GetUser))
+	r.GET("/user/cycles", appCtx.HandlerWrapper(ledger_handlers.GetUserCycles))
+	r.GET("/user/cycles/history", appCtx.HandlerWrapper(ledger_handlers.GetUserCyclesHistory))
+	r.GET("/instances", appCtx.HandlerWrapper(instance_handlers.GetInstances))
+	r.GET("/instances/events", appCtx.HandlerWrapper(instance_handlers.GetInstanceEvents))
+	r.GET("/instances/shared", appCtx.HandlerWrapper(instance_handlers.GetSharedInstances))
 	r.POST("/instance", appCtx.HandlerWrapper(instance_handlers.CreateInstance))
+	r.PATCH("/instance/:id", appCtx.HandlerWrapper(instance_handlers.UpdateInstance))
 	r.DELETE("/instance/:id", appCtx.HandlerWrapper(instance_handlers.TerminateInstance))
+	r.POST("/instance/:id/cancel-termination", appCtx.HandlerWrapper(instance_handlers.CancelInstanceTermination))
+	r.POST("/instance/:id/archive", appCtx.HandlerWrapper(instance_handlers.ArchiveInstance))
+	r.POST("/instance/:id/unarchive", appCtx.HandlerWrapper(instance_handlers.UnarchiveInstance))
 	r.POST("/instance/:id/start", appCtx.HandlerWrapper(instance_handlers.StartInstance))
 	r.POST("/instance/:id/stop", appCtx.HandlerWrapper(instance_handlers.StopInstance))
+	r.GET("/instance/:id/config", appCtx.HandlerWrapper(instance_handlers.GetInstanceConfig))
+	r.PUT("/instance/:id/config", appCtx.HandlerWrapper(instance_handlers.UpdateInstanceConfig))
+	r.PATCH("/instance/:id/plan", appCtx.HandlerWrapper(instance_handlers.ChangeInstancePlan))
+	r.GET("/instance/:id/members", appCtx.HandlerWrapper(instance_handlers.GetInstanceMembers))
+	r.DELETE("/instance/:id/members/:userId", appCtx.HandlerWrapper(instance_handlers.RemoveInstanceMember))
+	r.GET("/instance/:id/invites", appCtx.HandlerWrapper(instance_handlers.GetInstanceInvites))
+	r.POST("/instance/:id/invites", appCtx.HandlerWrapper(instance_handlers.CreateInstanceInvite))
+	r.DELETE("/instance/:id/invites/:inviteId", appCtx.HandlerWrapper(instance_handlers.RevokeInstanceInvite))
+	r.POST("/invites/:token/accept", appCtx.HandlerWrapper(instance_handlers.AcceptInstanceInvite))
+	r.GET("/instance/:id/activity", appCtx.HandlerWrapper(instance_handlers.GetInstanceActivity))
+	r.PUT("/instance/:id/idle-policy", appCtx.HandlerWrapper(instance_handlers.UpdateInstanceIdlePolicy))
+	r.PUT("/instance/:id/funding", appCtx.HandlerWrapper(instance_handlers.UpdateInstanceFunding))
+	r.GET("/instance/:id/status-history", appCtx.HandlerWrapper(instance_handlers.GetInstanceStatusHistory))
+	r.GET("/instance/:id/schedules", appCtx.HandlerWrapper(schedule_handlers.GetInstanceSchedules))
+	r.POST("/instance/:id/schedules", appCtx.HandlerWrapper(schedule_handlers.CreateInstanceSchedule))
+	r.DELETE("/instance/:id/schedules/:scheduleId", appCtx.HandlerWrapper(schedule_handlers.DeleteInstanceSchedule))
+	r.GET("/instance/:id/backups", appCtx.HandlerWrapper(backup_handlers.GetInstanceBackups))
+	r.POST("/instance/:id/backups", appCtx.HandlerWrapper(backup_handlers.CreateInstanceBackup))
+	r.POST("/instance/:id/backups/:backupId/restore", appCtx.HandlerWrapper(backup_handlers.RestoreInstanceBackup))
+	r.GET("/instance/:id/backup-policy", appCtx.HandlerWrapper(backup_handlers.GetInstanceBackupPolicy))
+	r.PUT("/instance/:id/backup-policy", appCtx.HandlerWrapper(backup_handlers.UpdateInstanceBackupPolicy))
+	r.GET("/instance/:id/cycles", appCtx.HandlerWrapper(ledger_handlers.GetInstanceCycles))
+	r.GET("/instance/:id/cycles/history", appCtx.HandlerWrapper(ledger_handlers.GetInstanceCyclesHistory))
 	r.GET("/services/:id", appCtx.HandlerWrapper(service_handlers.GetService))
+	r.POST("/purchases", appCtx.HandlerWrapper(payment_handlers.CreatePurchase))
+	r.GET("/purchases", appCtx.HandlerWrapper(payment_handlers.GetPurchases))
+	r.GET("/purchases/:id", appCtx.HandlerWrapper(payment_handlers.GetPurchase))
+	r.GET("/wallets", appCtx.HandlerWrapper(wallet_handlers.GetWallets))
+	r.POST("/wallets", appCtx.HandlerWrapper(wallet_handlers.CreateWallet))
+	r.GET("/wallets/:id", appCtx.HandlerWrapper(wallet_handlers.GetWallet))
+	r.GET("/wallets/:id/report", appCtx.HandlerWrapper(wallet_handlers.GetWalletReport))
+	r.POST("/wallets/:id/members", appCtx.HandlerWrapper(wallet_handlers.AddWalletMember))
+	r.DELETE("/wallets/:id/members/:userId", appCtx.HandlerWrapper(wallet_handlers.RemoveWalletMember))
+	r.POST("/wallets/:id/contributions", appCtx.HandlerWrapper(wallet_handlers.ContributeToWallet))
+	r.GET("/backups", appCtx.HandlerWrapper(backup_handlers.GetBackups))
+	r.DELETE("/backups/:id", appCtx.HandlerWrapper(backup_handlers.DeleteBackup))
+	r.GET("/notifications", appCtx.HandlerWrapper(notification_handlers.GetNotifications))
+	r.POST("/notifications/:id/read", appCtx.HandlerWrapper(notification_handlers.ReadNotification))
 
 	// Admin protected routes
 	r.Use(middlewares.RequireRole("admin"))
 	r.POST("/instance/rollout-update", appCtx.HandlerWrapper(instance_handlers.RolloutInstanceUpdate))
+	r.POST("/admin/cycles/grant", appCtx.HandlerWrapper(ledger_handlers.GrantCycles))
+	r.GET("/admin/reconciler/report", appCtx.HandlerWrapper(instance_handlers.GetReconcileReport))
+	r.POST("/admin/reconciler/run", appCtx.HandlerWrapper(instance_handlers.ReconcileInstances))
+	r.GET("/admin/plans", appCtx.HandlerWrapper(plan_handlers.GetPlans))
+	r.POST("/admin/plans", appCtx.HandlerWrapper(plan_handlers.CreatePlan))
+	r.PUT("/admin/plans/:id", appCtx.HandlerWrapper(plan_handlers.UpdatePlan))
+	r.POST("/admin/plans/:id/enable", appCtx.HandlerWrapper(plan_handlers.EnablePlan))
+	r.POST("/admin/plans/:id/disable", appCtx.HandlerWrapper(plan_handlers.DisablePlan))
+	r.DELETE("/admin/plans/:id", appCtx.HandlerWrapper(plan_handlers.DeletePlan))
+	r.POST("/admin/services", appCtx.HandlerWrapper(service_handlers.CreateService))
+	r.GET("/admin/services/lint", appCtx.HandlerWrapper(service_handlers.LintServicePresets))
+	r.GET("/admin/services/:id/presets", appCtx.HandlerWrapper(service_handlers.GetServicePresets))
+	r.POST("/admin/services/:id/presets", appCtx.HandlerWrapper(service_handlers.CreateServicePreset))
+	r.PUT("/admin/services/:id/presets/:version", appCtx.HandlerWrapper(service_handlers.UpdateServicePreset))
+	r.POST("/admin/services/:id/presets/:version/validate", appCtx.HandlerWrapper(service_handlers.ValidateServicePreset))
+	r.POST("/admin/services/:id/presets/:version/publish", appCtx.HandlerWrapper(service_handlers.PublishServicePreset))
 
 	return r
 }
 
 func setupInstanceRouter(appCtx *app.Context) *gin.Engine {
 	r := gin.Default()
+
+	// Every callback must carry the credential issued to the instance
+	r.Use(instance_middlewares.RequireInstanceCredential(appCtx))
+
 	r.GET("/startup/:id", appCtx.HandlerWrapper(instance_handlers.OnInstanceStartup))
 	r.POST("/shutdown/:id", appCtx.HandlerWrapper(instance_handlers.OnInstanceShutdown))
+	r.POST("/heartbeat/:id", appCtx.HandlerWrapper(instance_handlers.OnInstanceHeartbeat))
+	r.GET("/config/:id", appCtx.HandlerWrapper(instance_handlers.GetInstanceAgentConfig))
+	r.POST("/credentials/:id/rotate", appCtx.HandlerWrapper(instance_handlers.RotateInstanceCredential))
 	return r
 }
 
@@ -172,3 +319,34 @@ func startServer(router *gin.Engine, address string) {
 // 	fmt.Printf("%f cycles burned\n", request.BurnedCycles)
 // 	c.Status(http.StatusOK)
 // }
+
+func checkPlans(appCtx *app.Context) {
+	plans, err := appCtx.PlanRepository.GetPlans()
+	if err != nil {
+		log.Printf("Failed to check plans: %v", err)
+		return
+	}
+
+	for _, plan := range *plans {
+		if !plan.Enabled {
+			continue
+		}
+		if _, err := plan_validation.ValidatePlan(context.Background(), appCtx.InstanceClient, &plan); err != nil {
+			log.Printf("Warning: plan %d (%s) is invalid: %v", plan.ID, plan.Name, err)
+		}
+	}
+}
+
+func checkServicePresets(appCtx *app.Context) {
+	results, err := service_lint.LintPresets(appCtx.ServiceRepository)
+	if err != nil {
+		log.Printf("Failed to check service presets: %v", err)
+		return
+	}
+
+	for _, result := range results {
+		if !result.Valid {
+			log.Printf("Warning: preset %s %s is invalid: %s", result.NameID, result.Source, strings.Join(result.Errors, "; "))
+		}
+	}
+}
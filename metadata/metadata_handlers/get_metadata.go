@@ -1,11 +1,13 @@
 package metadata_handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-core/utils"
 	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_details"
 	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"github.com/mooncorn/gshub-main-api/user/user_models"
 )
@@ -37,16 +39,28 @@ func GetMetadata(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
-	plans, err := appCtx.PlanRepository.GetPlans()
+	plans, err := appCtx.PlanRepository.GetEnabledPlans()
 	if err != nil {
 		utils.HandleError(c, http.StatusInternalServerError, "Could not get plans", err, userEmail)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"user":      user,
 		"instances": instances,
 		"services":  services,
 		"plans":     plans,
-	})
+	}
+
+	// Live provider state is opt-in, a provider failure still returns the records
+	if instances != nil && c.Query("live") == "true" {
+		details, err := instance_details.Enrich(c, appCtx, *instances)
+		if err != nil {
+			log.Printf("%s: Failed to get live instance details: %v", userEmail, err)
+			response["liveError"] = "Live instance details are unavailable"
+		}
+		response["instances"] = details
+	}
+
+	c.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,35 @@
+package notification_delivery
+
+import (
+	"fmt"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_events"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+)
+
+// Notify stores a notification for the owner of the instance and pushes it to their event stream
+func Notify(appCtx *app.Context, instance *instance_models.Instance, kind notification_models.NotificationKind, message string) error {
+	instanceID := instance.ID
+	notification := notification_models.Notification{
+		UserID:     instance.UserID,
+		InstanceID: &instanceID,
+		Kind:       kind,
+		Message:    message,
+	}
+	if err := appCtx.NotificationRepository.CreateNotification(&notification); err != nil {
+		return err
+	}
+
+	appCtx.Events.Publish(instance.UserID, instance.ID, instance_events.EventNotification, notification)
+	return nil
+}
+
+// InstanceLabel names the instance in messages
+func InstanceLabel(instance *instance_models.Instance) string {
+	if instance.Name != "" {
+		return instance.Name
+	}
+	return fmt.Sprintf("#%d", instance.ID)
+}
@@ -0,0 +1,28 @@
+package notification_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetNotifications returns the user's latest notifications. Pass ?unread=true to skip read ones.
+func GetNotifications(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	notifications, err := appCtx.NotificationRepository.GetUserNotifications(user.ID, c.Query("unread") == "true", utils.MaxPageSize)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get notifications", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
@@ -0,0 +1,35 @@
+package notification_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// ReadNotification marks one of the user's notifications as read.
+func ReadNotification(c *gin.Context, appCtx *app.Context) {
+	notificationIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	notificationID64, err := strconv.ParseUint(notificationIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid notification id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	if err := appCtx.NotificationRepository.MarkNotificationRead(user.ID, uint(notificationID64)); err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Notification not found", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
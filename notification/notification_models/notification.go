@@ -0,0 +1,28 @@
+package notification_models
+
+import (
+	"time"
+)
+
+type NotificationKind string
+
+const (
+	NotificationKindCyclesLow       NotificationKind = "cycles_low"
+	NotificationKindCyclesExhausted NotificationKind = "cycles_exhausted"
+	NotificationKindIdleStopped     NotificationKind = "idle_stopped"
+	NotificationKindUnhealthy       NotificationKind = "unhealthy"
+	NotificationKindBackupRestored  NotificationKind = "backup_restored"
+	NotificationKindTerminated      NotificationKind = "terminated"
+	NotificationKindArchived        NotificationKind = "archived"
+)
+
+// Notification is a message for a user about one of their instances
+type Notification struct {
+	ID         uint             `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	UserID     uint             `gorm:"not null;index" json:"userId"`
+	InstanceID *uint            `json:"instanceId"`
+	Kind       NotificationKind `gorm:"not null" json:"kind"`
+	Message    string           `gorm:"not null" json:"message"`
+	ReadAt     *time.Time       `json:"readAt"`
+}
@@ -0,0 +1,47 @@
+package notification_repositories
+
+import (
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/notification/notification_models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	DB *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{DB: db}
+}
+
+func (r *NotificationRepository) CreateNotification(notification *notification_models.Notification) error {
+	return r.DB.Create(notification).Error
+}
+
+// GetUserNotifications returns the user's notifications, newest first
+func (r *NotificationRepository) GetUserNotifications(userID uint, unreadOnly bool, limit int) (*[]notification_models.Notification, error) {
+	query := r.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []notification_models.Notification
+	err := query.Order("id DESC").Limit(limit).Find(&notifications).Error
+	return &notifications, err
+}
+
+func (r *NotificationRepository) MarkNotificationRead(userID uint, notificationID uint) error {
+	result := r.DB.Model(&notification_models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
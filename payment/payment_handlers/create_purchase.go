@@ -0,0 +1,114 @@
+package payment_handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+const maxPurchaseCycles = 100000
+
+// The payload for buying cycles. Cycles go to the user's account unless an instance is given.
+type CreatePurchaseRequestBody struct {
+	Cycles     uint  `json:"cycles" binding:"required"`
+	InstanceID *uint `json:"instanceId"`
+}
+
+// CreatePurchase creates a payment intent for a number of cycles.
+func CreatePurchase(c *gin.Context, appCtx *app.Context) {
+	var request CreatePurchaseRequestBody
+
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	userEmail := c.GetString("userEmail")
+
+	if request.Cycles > maxPurchaseCycles {
+		utils.HandleError(c, http.StatusBadRequest, "Too many cycles", errors.New("cycles above purchase limit"), userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	if request.InstanceID != nil {
+		instance, err := appCtx.InstanceRepository.GetUserInstance(userEmail, *request.InstanceID)
+		if err != nil {
+			utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+			return
+		}
+
+		// Its own balance is not burned while a wallet funds it
+		if instance.WalletID != nil {
+			utils.HandleError(c, http.StatusConflict, "Instance is funded by a wallet, contribute to the wallet instead", errors.New("instance funded by wallet"), userEmail)
+			return
+		}
+	}
+
+	priceCents, err := strconv.ParseInt(os.Getenv("CYCLE_PRICE_CENTS"), 10, 64)
+	if err != nil || priceCents <= 0 {
+		utils.HandleError(c, http.StatusInternalServerError, "Cycle price is not configured", err, userEmail)
+		return
+	}
+
+	currency := strings.ToLower(os.Getenv("PAYMENT_CURRENCY"))
+	if currency == "" {
+		currency = "usd"
+	}
+
+	purchase := payment_models.Purchase{
+		UserID:      user.ID,
+		InstanceID:  request.InstanceID,
+		Cycles:      request.Cycles,
+		AmountCents: priceCents * int64(request.Cycles),
+		Currency:    currency,
+		Status:      payment_models.PurchaseStatusPending,
+		Provider:    appCtx.PaymentProvider.Name(),
+	}
+
+	if err := appCtx.PurchaseRepository.CreatePurchase(&purchase); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create purchase", err, userEmail)
+		return
+	}
+
+	intent, err := appCtx.PaymentProvider.CreateIntent(c, &purchase)
+	if err != nil {
+		purchase.Status = payment_models.PurchaseStatusFailed
+		appCtx.PurchaseRepository.SavePurchase(&purchase)
+		utils.HandleError(c, http.StatusBadGateway, "Failed to create payment", err, userEmail)
+		return
+	}
+
+	purchase.ProviderRef = intent.Ref
+	if err := appCtx.PurchaseRepository.SavePurchase(&purchase); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save purchase", err, userEmail)
+		return
+	}
+
+	// Some payments settle right away and never produce a webhook
+	settled := &purchase
+	if intent.Status != payment_models.PurchaseStatusPending {
+		settled, err = appCtx.PurchaseRepository.SettlePurchase(purchase.ID, intent.Status, appCtx.LedgerRepository)
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to settle purchase", err, userEmail)
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"purchase":     settled,
+		"clientSecret": intent.ClientSecret,
+	})
+}
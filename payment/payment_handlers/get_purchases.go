@@ -0,0 +1,55 @@
+package payment_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetPurchases returns the user's purchases, newest first.
+func GetPurchases(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	purchases, err := appCtx.PurchaseRepository.GetUserPurchases(user.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get purchases", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, purchases)
+}
+
+// GetPurchase returns one of the user's purchases, used to poll for settlement.
+func GetPurchase(c *gin.Context, appCtx *app.Context) {
+	purchaseIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	purchaseID64, err := strconv.ParseUint(purchaseIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid purchase id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	purchase, err := appCtx.PurchaseRepository.GetUserPurchase(user.ID, uint(purchaseID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Purchase not found", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, purchase)
+}
@@ -0,0 +1,45 @@
+package payment_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// PaymentWebhook receives payment status notifications from the payment provider.
+// Events for unknown or already settled purchases are acknowledged so they are not retried.
+func PaymentWebhook(c *gin.Context, appCtx *app.Context) {
+	provider := appCtx.PaymentProvider.Name()
+
+	payload, err := c.GetRawData()
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid request", err, provider)
+		return
+	}
+
+	event, err := appCtx.PaymentProvider.ParseWebhook(payload, c.Request.Header)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid webhook", err, provider)
+		return
+	}
+
+	if event == nil {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	purchase, err := appCtx.PurchaseRepository.GetPurchaseByProviderRef(provider, event.Ref)
+	if err != nil {
+		utils.HandleSuccess(c, http.StatusOK, "Ignored webhook for unknown payment "+event.Ref, provider)
+		return
+	}
+
+	if _, err := appCtx.PurchaseRepository.SettlePurchase(purchase.ID, event.Status, appCtx.LedgerRepository); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to settle purchase", err, provider)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,31 @@
+package payment_models
+
+import (
+	"time"
+)
+
+type PurchaseStatus string
+
+const (
+	PurchaseStatusPending   PurchaseStatus = "pending"
+	PurchaseStatusSucceeded PurchaseStatus = "succeeded"
+	PurchaseStatusFailed    PurchaseStatus = "failed"
+)
+
+// Purchase is a user's intent to buy cycles, settled by the payment provider
+type Purchase struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	UserID      uint           `gorm:"not null;index" json:"userId"`
+	InstanceID  *uint          `json:"instanceId"` // Credited instance, the user's account when empty
+	Cycles      uint           `gorm:"not null" json:"cycles"`
+	AmountCents int64          `gorm:"not null" json:"amountCents"`
+	Currency    string         `gorm:"not null" json:"currency"`
+	Status      PurchaseStatus `gorm:"not null;index" json:"status"`
+	Provider    string         `gorm:"not null" json:"provider"`
+	ProviderRef string         `gorm:"index" json:"providerRef"`
+	SettledAt   *time.Time     `json:"settledAt"`
+
+	LedgerTransactionID *uint `json:"ledgerTransactionId"` // Transaction crediting the cycles
+}
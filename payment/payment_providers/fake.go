@@ -0,0 +1,56 @@
+package payment_providers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
+)
+
+// FakeProvider settles payments locally without contacting any payment service.
+//
+// With auto settle enabled every intent succeeds immediately. Otherwise intents stay
+// pending until a webhook in Stripe's format, signed with SignPayload, reports them.
+type FakeProvider struct {
+	webhookSecret string
+	autoSettle    bool
+}
+
+func NewFakeProvider(webhookSecret string, autoSettle bool) *FakeProvider {
+	return &FakeProvider{
+		webhookSecret: webhookSecret,
+		autoSettle:    autoSettle,
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, purchase *payment_models.Purchase) (*Intent, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	intent := &Intent{
+		Ref:          "fake_pi_" + hex.EncodeToString(id),
+		ClientSecret: "fake_secret_" + hex.EncodeToString(id),
+		Status:       payment_models.PurchaseStatusPending,
+	}
+	if p.autoSettle {
+		intent.Status = payment_models.PurchaseStatusSucceeded
+	}
+
+	return intent, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := VerifySignature(payload, header.Get(stripeSignatureHeader), p.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+	return parseStripeEvent(payload)
+}
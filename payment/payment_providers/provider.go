@@ -0,0 +1,50 @@
+package payment_providers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
+)
+
+// Intent is a payment created by the provider for a purchase
+type Intent struct {
+	Ref          string                        // Provider side ID of the payment
+	ClientSecret string                        // Handed to the front-end to complete the payment
+	Status       payment_models.PurchaseStatus // Succeeded if the payment settled immediately
+}
+
+// WebhookEvent is a verified payment status notification
+type WebhookEvent struct {
+	ID     string
+	Ref    string
+	Status payment_models.PurchaseStatus
+}
+
+// PaymentProvider creates payments and verifies their status notifications
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, purchase *payment_models.Purchase) (*Intent, error)
+
+	// ParseWebhook verifies the notification signature and returns the event.
+	// A nil event means the notification is valid but not relevant.
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+// NewPaymentProvider returns the provider selected by PAYMENT_PROVIDER ("fake" or "stripe").
+// There is no default, a missing provider must not hand out cycles without payment.
+// The fake only settles purchases by itself when FAKE_PAYMENT_AUTO_SETTLE is "true".
+func NewPaymentProvider() PaymentProvider {
+	switch name := strings.ToLower(os.Getenv("PAYMENT_PROVIDER")); name {
+	case "stripe":
+		return NewStripeProvider(os.Getenv("STRIPE_SECRET_KEY"), os.Getenv("PAYMENT_WEBHOOK_SECRET"))
+	case "fake":
+		return NewFakeProvider(os.Getenv("PAYMENT_WEBHOOK_SECRET"), os.Getenv("FAKE_PAYMENT_AUTO_SETTLE") == "true")
+	default:
+		log.Fatalf("Invalid PAYMENT_PROVIDER %q, expected \"fake\" or \"stripe\"", name)
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package payment_providers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
+)
+
+const (
+	stripeAPIURL             = "https://api.stripe.com/v1"
+	stripeSignatureHeader    = "Stripe-Signature"
+	stripeSignatureTolerance = 5 * time.Minute
+)
+
+// StripeProvider creates Stripe payment intents and verifies Stripe webhooks
+type StripeProvider struct {
+	apiKey        string
+	webhookSecret string
+	httpClient    *http.Client
+}
+
+func NewStripeProvider(apiKey string, webhookSecret string) *StripeProvider {
+	return &StripeProvider{
+		apiKey:        apiKey,
+		webhookSecret: webhookSecret,
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *StripeProvider) Name() string {
+	return "stripe"
+}
+
+func (p *StripeProvider) CreateIntent(ctx context.Context, purchase *payment_models.Purchase) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.FormatInt(purchase.AmountCents, 10))
+	form.Set("currency", strings.ToLower(purchase.Currency))
+	form.Set("automatic_payment_methods[enabled]", "true")
+	form.Set("metadata[purchase_id]", strconv.FormatUint(uint64(purchase.ID), 10))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, stripeAPIURL+"/payment_intents", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(p.apiKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Idempotency-Key", fmt.Sprintf("purchase-%d", purchase.ID))
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment intent: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create payment intent: stripe returned %s", res.Status)
+	}
+
+	var intent stripePaymentIntent
+	if err := json.NewDecoder(res.Body).Decode(&intent); err != nil {
+		return nil, fmt.Errorf("failed to decode payment intent: %v", err)
+	}
+
+	return &Intent{
+		Ref:          intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Status:       intent.purchaseStatus(),
+	}, nil
+}
+
+func (p *StripeProvider) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	if err := VerifySignature(payload, header.Get(stripeSignatureHeader), p.webhookSecret, time.Now()); err != nil {
+		return nil, err
+	}
+	return parseStripeEvent(payload)
+}
+
+type stripePaymentIntent struct {
+	ID           string `json:"id"`
+	ClientSecret string `json:"client_secret"`
+	Status       string `json:"status"`
+}
+
+func (i stripePaymentIntent) purchaseStatus() payment_models.PurchaseStatus {
+	switch i.Status {
+	case "succeeded":
+		return payment_models.PurchaseStatusSucceeded
+	case "canceled":
+		return payment_models.PurchaseStatusFailed
+	default:
+		return payment_models.PurchaseStatusPending
+	}
+}
+
+type stripeEvent struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object stripePaymentIntent `json:"object"`
+	} `json:"data"`
+}
+
+func parseStripeEvent(payload []byte) (*WebhookEvent, error) {
+	var event stripeEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event: %v", err)
+	}
+
+	var status payment_models.PurchaseStatus
+	switch event.Type {
+	case "payment_intent.succeeded":
+		status = payment_models.PurchaseStatusSucceeded
+	case "payment_intent.payment_failed", "payment_intent.canceled":
+		status = payment_models.PurchaseStatusFailed
+	default:
+		return nil, nil
+	}
+
+	return &WebhookEvent{
+		ID:     event.ID,
+		Ref:    event.Data.Object.ID,
+		Status: status,
+	}, nil
+}
+
+// VerifySignature checks a Stripe style signature header ("t=<unix>,v1=<hex hmac>")
+// where the HMAC-SHA256 is computed over "<t>.<payload>" with the webhook secret.
+func VerifySignature(payload []byte, signatureHeader string, secret string, now time.Time) error {
+	if secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("malformed signature header")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return errors.New("signature timestamp outside of tolerance")
+	}
+
+	expected := computeSignature(timestamp, payload, secret)
+	for _, signature := range signatures {
+		decoded, err := hex.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+
+	return errors.New("signature mismatch")
+}
+
+// SignPayload builds a signature header accepted by VerifySignature
+func SignPayload(payload []byte, secret string, now time.Time) string {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", timestamp, hex.EncodeToString(computeSignature(timestamp, payload, secret)))
+}
+
+func computeSignature(timestamp string, payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,91 @@
+package payment_repositories
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/payment/payment_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PurchaseRepository struct {
+	DB *gorm.DB
+}
+
+func NewPurchaseRepository(db *gorm.DB) *PurchaseRepository {
+	return &PurchaseRepository{DB: db}
+}
+
+func (r *PurchaseRepository) CreatePurchase(purchase *payment_models.Purchase) error {
+	return r.DB.Create(purchase).Error
+}
+
+func (r *PurchaseRepository) SavePurchase(purchase *payment_models.Purchase) error {
+	return r.DB.Save(purchase).Error
+}
+
+func (r *PurchaseRepository) GetUserPurchase(userID uint, purchaseID uint) (*payment_models.Purchase, error) {
+	var purchase payment_models.Purchase
+	err := r.DB.Where("id = ? AND user_id = ?", purchaseID, userID).First(&purchase).Error
+	return &purchase, err
+}
+
+func (r *PurchaseRepository) GetUserPurchases(userID uint) (*[]payment_models.Purchase, error) {
+	var purchases []payment_models.Purchase
+	err := r.DB.Where("user_id = ?", userID).Order("id DESC").Find(&purchases).Error
+	return &purchases, err
+}
+
+func (r *PurchaseRepository) GetPurchaseByProviderRef(provider string, ref string) (*payment_models.Purchase, error) {
+	var purchase payment_models.Purchase
+	err := r.DB.Where("provider = ? AND provider_ref = ?", provider, ref).First(&purchase).Error
+	return &purchase, err
+}
+
+// SettlePurchase records the final status of a purchase and credits its cycles when it succeeded.
+// Settling an already settled purchase has no effect, so repeated webhooks are harmless.
+func (r *PurchaseRepository) SettlePurchase(purchaseID uint, status payment_models.PurchaseStatus, ledger *ledger_repositories.LedgerRepository) (*payment_models.Purchase, error) {
+	var purchase payment_models.Purchase
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&purchase, purchaseID).Error; err != nil {
+			return err
+		}
+
+		if purchase.Status != payment_models.PurchaseStatusPending || status == payment_models.PurchaseStatusPending {
+			return nil
+		}
+
+		now := time.Now()
+		purchase.Status = status
+		purchase.SettledAt = &now
+
+		if status == payment_models.PurchaseStatusSucceeded {
+			account := ledger_repositories.UserAccount(purchase.UserID)
+			if purchase.InstanceID != nil {
+				account = ledger_repositories.InstanceAccount(*purchase.InstanceID)
+			}
+
+			transaction, err := ledger.TransferTx(tx, ledger_repositories.Transfer{
+				From:           ledger_repositories.SystemAccount(ledger_models.SystemAccountPurchases),
+				To:             account,
+				Amount:         purchase.Cycles,
+				Reason:         ledger_models.ReasonPurchase,
+				Description:    fmt.Sprintf("Purchase #%d", purchase.ID),
+				Actor:          purchase.Provider,
+				IdempotencyKey: fmt.Sprintf("purchase:%d", purchase.ID),
+			})
+			if err != nil {
+				return err
+			}
+			purchase.LedgerTransactionID = &transaction.ID
+		}
+
+		return tx.Save(&purchase).Error
+	})
+
+	return &purchase, err
+}
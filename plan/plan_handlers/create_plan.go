@@ -0,0 +1,55 @@
+package plan_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/plan/plan_models"
+	"github.com/mooncorn/gshub-main-api/plan/plan_validation"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for creating or updating a plan
+type PlanRequestBody struct {
+	Name         string  `json:"name" binding:"required"`
+	InstanceType string  `json:"instanceType" binding:"required"`
+	VCores       int     `json:"vCores" binding:"required"`
+	Memory       int     `json:"memory" binding:"required"`
+	Disk         int     `json:"disk" binding:"required"`
+	Price        float64 `json:"price" binding:"gte=0"`
+	Enabled      bool    `json:"enabled"`
+}
+
+// CreatePlan adds a plan after validating it against the capabilities of its instance type.
+func CreatePlan(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	var request PlanRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	plan := plan_models.Plan{
+		Name:         request.Name,
+		InstanceType: request.InstanceType,
+		VCores:       request.VCores,
+		Memory:       request.Memory,
+		Disk:         request.Disk,
+		Price:        request.Price,
+		Enabled:      request.Enabled,
+	}
+
+	if _, err := plan_validation.ValidatePlan(c, appCtx.InstanceClient, &plan); err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan", err, userEmail)
+		return
+	}
+
+	if err := appCtx.PlanRepository.CreatePlan(&plan); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create plan", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
@@ -0,0 +1,39 @@
+package plan_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/plan/plan_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"gorm.io/gorm"
+)
+
+// DeletePlan soft deletes a plan that no instance uses anymore
+func DeletePlan(c *gin.Context, appCtx *app.Context) {
+	planIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	planID64, err := strconv.ParseUint(planIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan id", err, userEmail)
+		return
+	}
+
+	if err := appCtx.PlanRepository.DeletePlan(uint(planID64)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.HandleError(c, http.StatusNotFound, "Plan not found", err, userEmail)
+		case errors.Is(err, plan_repositories.ErrPlanInUse):
+			utils.HandleError(c, http.StatusConflict, "Plan is used by instances", err, userEmail)
+		default:
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to delete plan", err, userEmail)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
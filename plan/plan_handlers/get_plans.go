@@ -0,0 +1,22 @@
+package plan_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetPlans returns every plan including disabled ones
+func GetPlans(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	plans, err := appCtx.PlanRepository.GetPlans()
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Could not get plans", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
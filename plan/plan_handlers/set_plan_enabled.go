@@ -0,0 +1,54 @@
+package plan_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/plan/plan_validation"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// EnablePlan makes the plan available for new instances
+func EnablePlan(c *gin.Context, appCtx *app.Context) {
+	setPlanEnabled(c, appCtx, true)
+}
+
+// DisablePlan hides the plan from new instances, existing instances keep running on it
+func DisablePlan(c *gin.Context, appCtx *app.Context) {
+	setPlanEnabled(c, appCtx, false)
+}
+
+func setPlanEnabled(c *gin.Context, appCtx *app.Context, enabled bool) {
+	planIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	planID64, err := strconv.ParseUint(planIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan id", err, userEmail)
+		return
+	}
+
+	plan, err := appCtx.PlanRepository.GetPlan(uint(planID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Plan not found", err, userEmail)
+		return
+	}
+
+	// The instance type may have been retired since the plan was created
+	if enabled {
+		if _, err := plan_validation.ValidatePlan(c, appCtx.InstanceClient, plan); err != nil {
+			utils.HandleError(c, http.StatusBadRequest, "Invalid plan", err, userEmail)
+			return
+		}
+	}
+
+	if err := appCtx.PlanRepository.SetPlanEnabled(plan, enabled); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to update plan", err, userEmail)
+		return
+	}
+	plan.Enabled = enabled
+
+	c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,75 @@
+package plan_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/plan/plan_validation"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// UpdatePlan replaces the plan's fields. Name, price and availability can always change,
+// the hardware of a plan used by instances cannot since they would no longer match it.
+// New prices apply to running instances from the next metering run.
+func UpdatePlan(c *gin.Context, appCtx *app.Context) {
+	planIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	planID64, err := strconv.ParseUint(planIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan id", err, userEmail)
+		return
+	}
+
+	var request PlanRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	plan, err := appCtx.PlanRepository.GetPlan(uint(planID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Plan not found", err, userEmail)
+		return
+	}
+
+	hardwareChanged := plan.InstanceType != request.InstanceType ||
+		plan.VCores != request.VCores ||
+		plan.Memory != request.Memory ||
+		plan.Disk != request.Disk
+
+	if hardwareChanged {
+		count, err := appCtx.PlanRepository.CountPlanInstances(plan.ID)
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to check plan usage", err, userEmail)
+			return
+		}
+		if count > 0 {
+			utils.HandleError(c, http.StatusConflict, "Plan is used by instances, create a new plan instead", errors.New("hardware change on plan in use"), userEmail)
+			return
+		}
+	}
+
+	plan.Name = request.Name
+	plan.InstanceType = request.InstanceType
+	plan.VCores = request.VCores
+	plan.Memory = request.Memory
+	plan.Disk = request.Disk
+	plan.Price = request.Price
+	plan.Enabled = request.Enabled
+
+	if _, err := plan_validation.ValidatePlan(c, appCtx.InstanceClient, plan); err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid plan", err, userEmail)
+		return
+	}
+
+	if err := appCtx.PlanRepository.SavePlan(plan); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save plan", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
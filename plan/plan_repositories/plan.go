@@ -1,10 +1,16 @@
 package plan_repositories
 
 import (
+	"errors"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
 	"github.com/mooncorn/gshub-main-api/plan/plan_models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrPlanInUse = errors.New("plan is used by instances")
+
 type PlanRepository struct {
 	DB *gorm.DB
 }
@@ -24,3 +30,49 @@ func (r *PlanRepository) GetPlans() (*[]plan_models.Plan, error) {
 	err := r.DB.Find(&plans).Error
 	return &plans, err
 }
+
+// GetEnabledPlans returns the plans that can be used for new instances
+func (r *PlanRepository) GetEnabledPlans() (*[]plan_models.Plan, error) {
+	var plans []plan_models.Plan
+	err := r.DB.Where("enabled = ?", true).Find(&plans).Error
+	return &plans, err
+}
+
+func (r *PlanRepository) CreatePlan(plan *plan_models.Plan) error {
+	return r.DB.Create(plan).Error
+}
+
+func (r *PlanRepository) SavePlan(plan *plan_models.Plan) error {
+	return r.DB.Save(plan).Error
+}
+
+func (r *PlanRepository) SetPlanEnabled(plan *plan_models.Plan, enabled bool) error {
+	return r.DB.Model(plan).Update("enabled", enabled).Error
+}
+
+// CountPlanInstances returns the number of instances that were not deleted using the plan
+func (r *PlanRepository) CountPlanInstances(planID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&instance_models.Instance{}).Where("plan_id = ?", planID).Count(&count).Error
+	return count, err
+}
+
+// DeletePlan soft deletes the plan, ErrPlanInUse is returned while instances still use it
+func (r *PlanRepository) DeletePlan(planID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var plan plan_models.Plan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, planID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&instance_models.Instance{}).Where("plan_id = ?", planID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPlanInUse
+		}
+
+		return tx.Delete(&plan).Error
+	})
+}
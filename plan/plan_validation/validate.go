@@ -0,0 +1,42 @@
+package plan_validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_aws"
+	"github.com/mooncorn/gshub-main-api/plan/plan_models"
+)
+
+// ValidatePlan checks the plan against the capabilities of its instance type.
+// The type must be in an allowed family and offered in the region, the plan's cores
+// must match the type and its memory cannot exceed what the type provides.
+func ValidatePlan(ctx context.Context, client instance_aws.InstanceClient, plan *plan_models.Plan) (*instance_aws.InstanceTypeInfo, error) {
+	if _, err := instance_aws.ParseInstanceType(plan.InstanceType); err != nil {
+		return nil, err
+	}
+
+	info, err := client.DescribeInstanceType(ctx, plan.InstanceType)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.Available {
+		return nil, fmt.Errorf("instance type %s is not offered in this region", plan.InstanceType)
+	}
+
+	if plan.VCores != int(info.VCPUs) {
+		return nil, fmt.Errorf("instance type %s has %d vCPUs, plan has %d", plan.InstanceType, info.VCPUs, plan.VCores)
+	}
+
+	if plan.Memory <= 0 || int64(plan.Memory) > info.MemoryMiB {
+		return nil, fmt.Errorf("instance type %s has %d MiB of memory, plan has %d", plan.InstanceType, info.MemoryMiB, plan.Memory)
+	}
+
+	if plan.Disk <= 0 {
+		return nil, errors.New("disk must be positive")
+	}
+
+	return info, nil
+}
@@ -0,0 +1,216 @@
+package schedule_cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Schedules use IANA time zones, the host may not have the database installed
+	_ "time/tzdata"
+)
+
+// Expression is a parsed standard five field cron expression:
+// minute hour day-of-month month day-of-week
+type Expression struct {
+	minute, hour, dom, month, dow uint64 // Bit i is set when value i matches
+	domStar, dowStar              bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Parse parses an expression such as "30 18 * * mon-fri" or one of @hourly, @daily,
+// @weekly and @monthly. Fields accept *, values, names, ranges, steps and lists.
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := macros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	var e Expression
+	var err error
+	if e.minute, err = minuteField.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("minute: %v", err)
+	}
+	if e.hour, err = hourField.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("hour: %v", err)
+	}
+	if e.dom, err = domField.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("day of month: %v", err)
+	}
+	if e.month, err = monthField.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("month: %v", err)
+	}
+	if e.dow, err = dowField.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("day of week: %v", err)
+	}
+
+	// Sunday can be written as 7
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+	e.domStar = fields[2] == "*"
+	e.dowStar = fields[4] == "*"
+
+	return &e, nil
+}
+
+func (f field) parse(spec string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(spec, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepSpec); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepSpec)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangeSpec == "*":
+			start, end = f.min, f.max
+		case strings.Contains(rangeSpec, "-"):
+			low, high, _ := strings.Cut(rangeSpec, "-")
+			var err error
+			if start, err = f.value(low); err != nil {
+				return 0, err
+			}
+			if end, err = f.value(high); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rangeSpec)
+			}
+		default:
+			var err error
+			if start, err = f.value(rangeSpec); err != nil {
+				return 0, err
+			}
+			end = start
+			// "5/15" means every 15 starting at 5
+			if hasStep {
+				end = f.max
+			}
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (f field) value(spec string) (int, error) {
+	if value, ok := f.names[strings.ToLower(spec)]; ok {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(spec)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid value %q", spec)
+	}
+	return value, nil
+}
+
+// Next returns the first matching time after t in t's location,
+// or the zero time if the expression does not match within five years.
+func (e *Expression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for e.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !e.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for e.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for e.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches follows cron: when both day fields are restricted either may match
+func (e *Expression) dayMatches(t time.Time) bool {
+	domMatch := e.dom&(1<<uint(t.Day())) != 0
+	dowMatch := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domStar || e.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// NextRun returns the first time after t matching the expression evaluated in the IANA time zone
+func NextRun(spec string, timeZone string, t time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time zone %q", timeZone)
+	}
+
+	e, err := Parse(spec)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := e.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("expression %q never matches", spec)
+	}
+	return next, nil
+}
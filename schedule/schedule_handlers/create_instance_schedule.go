@@ -0,0 +1,86 @@
+package schedule_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/instance/instance_repositories"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_cron"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+const maxSchedulesPerInstance = 10
+
+// The payload for creating a schedule
+type CreateInstanceScheduleRequestBody struct {
+	Action   schedule_models.ScheduleAction `json:"action" binding:"required,oneof=start stop"`
+	Cron     string                         `json:"cron" binding:"required"`
+	TimeZone string                         `json:"timeZone" binding:"required"`
+}
+
+// CreateInstanceSchedule adds a schedule starting or stopping the user's instance
+func CreateInstanceSchedule(c *gin.Context, appCtx *app.Context) {
+	instanceIDStr := c.Param("id")
+	userEmail := c.GetString("userEmail")
+
+	instanceID64, err := strconv.ParseUint(instanceIDStr, 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid instance id", err, userEmail)
+		return
+	}
+
+	var request CreateInstanceScheduleRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	// Check if the instance exists
+	instance, _, err := appCtx.InstanceRepository.GetMemberInstance(userEmail, uint(instanceID64), instance_models.InstanceRoleOperator)
+	if errors.Is(err, instance_repositories.ErrInsufficientRole) {
+		utils.HandleError(c, http.StatusForbidden, "Your role on this instance does not allow this", err, userEmail)
+		return
+	}
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Instance not found", err, userEmail)
+		return
+	}
+
+	nextRunAt, err := schedule_cron.NextRun(request.Cron, request.TimeZone, time.Now())
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid schedule", err, userEmail)
+		return
+	}
+
+	count, err := appCtx.ScheduleRepository.CountInstanceSchedules(instance.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get schedules", err, userEmail)
+		return
+	}
+	if count >= maxSchedulesPerInstance {
+		utils.HandleError(c, http.StatusBadRequest, "Too many schedules", errors.New("schedule limit reached"), userEmail)
+		return
+	}
+
+	schedule := schedule_models.InstanceSchedule{
+		InstanceID: instance.ID,
+		Action:     request.Action,
+		Cron:       request.Cron,
+		TimeZone:   request.TimeZone,
+		CreatedBy:  userEmail,
+		NextRunAt:  nextRunAt,
+	}
+
+	if err := appCtx.ScheduleRepository.CreateSchedule(&schedule); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create schedule", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
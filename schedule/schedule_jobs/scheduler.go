@@ -0,0 +1,135 @@
+package schedule_jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/instance/instance_billing"
+	"github.com/mooncorn/gshub-main-api/instance/instance_lifecycle"
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_cron"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_models"
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_repositories"
+	"gorm.io/gorm"
+)
+
+// Runs found later than this after their time, e.g. after downtime, are skipped
+const misfireGrace = 10 * time.Minute
+
+// RunScheduler runs the due instance schedules every interval. It never returns.
+// Schedules are stored with their next run so runs due during a restart are picked up.
+func RunScheduler(appCtx *app.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		schedules, err := appCtx.ScheduleRepository.GetDueSchedules(now)
+		if err != nil {
+			log.Printf("scheduler: Error: failed to get schedules: %v", err)
+		} else {
+			for i := range *schedules {
+				schedule := &(*schedules)[i]
+				if err := runSchedule(context.Background(), appCtx, schedule, now); err != nil {
+					log.Printf("scheduler: Error: schedule %d: %v", schedule.ID, err)
+				}
+			}
+		}
+
+		<-ticker.C
+	}
+}
+
+func runSchedule(ctx context.Context, appCtx *app.Context, schedule *schedule_models.InstanceSchedule, now time.Time) error {
+	due := schedule.NextRunAt
+
+	// Time zone data changed or the expression stopped matching, it cannot run again.
+	// The due run still happens and the reason is added to its result.
+	var disabled string
+	nextRunAt, err := schedule_cron.NextRun(schedule.Cron, schedule.TimeZone, now)
+	if err != nil {
+		disabled = fmt.Sprintf("disabled: %v", err)
+		nextRunAt = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	if err := appCtx.ScheduleRepository.ClaimScheduleRun(schedule, nextRunAt, now); err != nil {
+		if errors.Is(err, schedule_repositories.ErrScheduleClaimed) {
+			return nil
+		}
+		return err
+	}
+
+	instance, err := appCtx.InstanceRepository.GetInstance(schedule.InstanceID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The instance was terminated
+		return appCtx.ScheduleRepository.DeleteSchedule(schedule.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := "skipped: missed by " + now.Sub(due).Round(time.Minute).String()
+	if now.Sub(due) <= misfireGrace {
+		result = perform(ctx, appCtx, schedule, instance)
+	}
+	if disabled != "" {
+		result += "; " + disabled
+	}
+
+	return appCtx.ScheduleRepository.SetScheduleResult(schedule, result)
+}
+
+// perform runs the schedule's action and describes what happened
+func perform(ctx context.Context, appCtx *app.Context, schedule *schedule_models.InstanceSchedule, instance *instance_models.Instance) string {
+	actor := fmt.Sprintf("schedule:%d", schedule.ID)
+	reason := fmt.Sprintf("scheduled %s (%s %s)", schedule.Action, schedule.Cron, schedule.TimeZone)
+
+	switch instance.Status {
+	case instance_models.InstanceStatusPendingDeletion, instance_models.InstanceStatusArchiving, instance_models.InstanceStatusArchived:
+		return "skipped: " + string(instance.Status)
+	}
+
+	switch schedule.Action {
+	case schedule_models.ScheduleActionStart:
+		switch instance.Status {
+		case instance_models.InstanceStatusStarting, instance_models.InstanceStatusRunning:
+			return "skipped: already " + string(instance.Status)
+		}
+
+		// Do not start instances the metering job would stop right away
+		plan, err := appCtx.PlanRepository.GetPlan(instance.PlanID)
+		if err != nil {
+			return fmt.Sprintf("failed: %v", err)
+		}
+		if instance_billing.CyclesPerHour(plan) > 0 {
+			balance, err := appCtx.LedgerRepository.GetBalance(instance_billing.FundingAccount(instance))
+			if err != nil {
+				return fmt.Sprintf("failed: %v", err)
+			}
+			if balance <= 0 {
+				return "skipped: no cycles left"
+			}
+		}
+
+		if err := instance_lifecycle.StartInstance(ctx, appCtx, instance, actor, reason); err != nil {
+			return fmt.Sprintf("failed: %v", err)
+		}
+		return "started"
+	case schedule_models.ScheduleActionStop:
+		switch instance.Status {
+		case instance_models.InstanceStatusStopping, instance_models.InstanceStatusStopped:
+			return "skipped: already " + string(instance.Status)
+		}
+
+		if err := instance_lifecycle.StopInstance(ctx, appCtx, instance, actor, reason); err != nil {
+			return fmt.Sprintf("failed: %v", err)
+		}
+		return "stopped"
+	default:
+		return fmt.Sprintf("failed: unknown action %s", schedule.Action)
+	}
+}
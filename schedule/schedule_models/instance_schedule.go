@@ -0,0 +1,28 @@
+package schedule_models
+
+import (
+	"time"
+)
+
+type ScheduleAction string
+
+const (
+	ScheduleActionStart ScheduleAction = "start"
+	ScheduleActionStop  ScheduleAction = "stop"
+)
+
+// InstanceSchedule starts or stops an instance at the times matching a cron expression
+type InstanceSchedule struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	InstanceID uint           `gorm:"not null;index" json:"instanceId"`
+	Action     ScheduleAction `gorm:"not null" json:"action"`
+	Cron       string         `gorm:"not null" json:"cron"`     // Five field cron expression
+	TimeZone   string         `gorm:"not null" json:"timeZone"` // IANA time zone the expression is evaluated in
+	CreatedBy  string         `gorm:"not null" json:"createdBy"`
+
+	NextRunAt  time.Time  `gorm:"not null;index" json:"nextRunAt"`
+	LastRunAt  *time.Time `json:"lastRunAt"`
+	LastResult string     `json:"lastResult"` // What the last run did, e.g. "started" or "skipped: already running"
+}
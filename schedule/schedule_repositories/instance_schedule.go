@@ -0,0 +1,93 @@
+package schedule_repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mooncorn/gshub-main-api/schedule/schedule_models"
+	"gorm.io/gorm"
+)
+
+// ErrScheduleClaimed is returned when another scheduler already claimed the run
+var ErrScheduleClaimed = errors.New("schedule run already claimed")
+
+type ScheduleRepository struct {
+	DB *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) *ScheduleRepository {
+	return &ScheduleRepository{DB: db}
+}
+
+func (r *ScheduleRepository) GetInstanceSchedules(instanceID uint) (*[]schedule_models.InstanceSchedule, error) {
+	var schedules []schedule_models.InstanceSchedule
+	err := r.DB.Where("instance_id = ?", instanceID).Order("id").Find(&schedules).Error
+	return &schedules, err
+}
+
+func (r *ScheduleRepository) CountInstanceSchedules(instanceID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&schedule_models.InstanceSchedule{}).Where("instance_id = ?", instanceID).Count(&count).Error
+	return count, err
+}
+
+func (r *ScheduleRepository) CreateSchedule(schedule *schedule_models.InstanceSchedule) error {
+	return r.DB.Create(schedule).Error
+}
+
+func (r *ScheduleRepository) DeleteInstanceSchedule(instanceID uint, scheduleID uint) error {
+	result := r.DB.Where("id = ? AND instance_id = ?", scheduleID, instanceID).Delete(&schedule_models.InstanceSchedule{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *ScheduleRepository) DeleteSchedule(scheduleID uint) error {
+	return r.DB.Delete(&schedule_models.InstanceSchedule{}, scheduleID).Error
+}
+
+// DeleteInstanceSchedules deletes all schedules of the instance
+func (r *ScheduleRepository) DeleteInstanceSchedules(instanceID uint) error {
+	return r.DB.Where("instance_id = ?", instanceID).Delete(&schedule_models.InstanceSchedule{}).Error
+}
+
+// GetDueSchedules returns the schedules whose next run is at or before now
+func (r *ScheduleRepository) GetDueSchedules(now time.Time) (*[]schedule_models.InstanceSchedule, error) {
+	var schedules []schedule_models.InstanceSchedule
+	err := r.DB.Where("next_run_at <= ?", now).Order("next_run_at").Find(&schedules).Error
+	return &schedules, err
+}
+
+// ClaimScheduleRun moves the schedule to its next run unless another scheduler did so first,
+// in which case ErrScheduleClaimed is returned. Claiming before acting means a run is
+// skipped rather than repeated when the process dies in between.
+func (r *ScheduleRepository) ClaimScheduleRun(schedule *schedule_models.InstanceSchedule, nextRunAt time.Time, now time.Time) error {
+	result := r.DB.Model(&schedule_models.InstanceSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+		Updates(map[string]interface{}{
+			"next_run_at": nextRunAt,
+			"last_run_at": now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrScheduleClaimed
+	}
+
+	schedule.NextRunAt = nextRunAt
+	schedule.LastRunAt = &now
+	return nil
+}
+
+func (r *ScheduleRepository) SetScheduleResult(schedule *schedule_models.InstanceSchedule, result string) error {
+	schedule.LastResult = result
+	return r.DB.Model(schedule).Update("last_result", result).Error
+}
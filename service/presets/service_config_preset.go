@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 )
 
 type ServiceConfiguration struct {
@@ -21,12 +22,21 @@ type ServiceConfiguration struct {
 type Env struct {
 	Name        string  `json:"name"`
 	Key         string  `json:"key"`
+	Type        EnvType `json:"type,omitempty"` // Defaults to string
 	Required    bool    `json:"required"`
 	Description string  `json:"description"`
 	Default     string  `json:"default"`
 	Values      []Value `json:"values"`
 }
 
+type EnvType string
+
+const (
+	EnvTypeString  EnvType = "string"
+	EnvTypeNumber  EnvType = "number"
+	EnvTypeBoolean EnvType = "boolean"
+)
+
 type Value struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
@@ -43,9 +53,13 @@ type Volume struct {
 	Destination string `json:"destination"`
 }
 
+// SeedFile holds the presets used to seed services without any preset version
+const SeedFile = "./service/presets/service-configurations.json"
+
+// GetServiceConfigurations reads the configurations keyed by service name id from the seed file
 func GetServiceConfigurations() (map[string]ServiceConfiguration, error) {
 	// Open the JSON file
-	jsonFile, err := os.Open("./service/presets/service-configurations.json")
+	jsonFile, err := os.Open(SeedFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open json file: %v", err)
 	}
@@ -66,16 +80,65 @@ func GetServiceConfigurations() (map[string]ServiceConfiguration, error) {
 	return configs, nil
 }
 
-func GetServiceConfiguration(serviceNameID string) (ServiceConfiguration, error) {
-	configs, err := GetServiceConfigurations()
-	if err != nil {
-		return ServiceConfiguration{}, fmt.Errorf("failed to get server configs: %s: %v", serviceNameID, err)
+// ResolveEnv validates user supplied env values against the configuration and fills in
+// defaults for the keys that were not provided.
+func (c ServiceConfiguration) ResolveEnv(values map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(c.Env))
+	known := make(map[string]Env, len(c.Env))
+	for _, env := range c.Env {
+		known[env.Key] = env
 	}
 
-	serviceConfig, exists := configs[serviceNameID]
-	if !exists {
-		return ServiceConfiguration{}, fmt.Errorf("config for this service name id not found: %s: %v", serviceNameID, err)
+	for key := range values {
+		if _, exists := known[key]; !exists {
+			return nil, fmt.Errorf("unknown env key: %s", key)
+		}
 	}
 
-	return serviceConfig, nil
+	for _, env := range c.Env {
+		value, provided := values[env.Key]
+		if !provided {
+			value = env.Default
+		}
+
+		if value == "" {
+			if env.Required {
+				return nil, fmt.Errorf("missing required env key: %s", env.Key)
+			}
+			continue
+		}
+
+		if !env.allows(value) {
+			return nil, fmt.Errorf("invalid value for env key %s: %s", env.Key, value)
+		}
+
+		resolved[env.Key] = value
+	}
+
+	return resolved, nil
+}
+
+// allows reports whether value has the env's type and is one of the enumerated values, if the env has any
+func (e Env) allows(value string) bool {
+	switch e.Type {
+	case EnvTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return false
+		}
+	case EnvTypeBoolean:
+		if value != "true" && value != "false" {
+			return false
+		}
+	}
+
+	if len(e.Values) == 0 {
+		return true
+	}
+
+	for _, v := range e.Values {
+		if v.Value == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,126 @@
+package presets
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// VolumeRoot is the host directory the volumes of a service must be in,
+// it keeps services from mounting system paths or each other's data
+func VolumeRoot(nameID string) string {
+	return "/" + nameID
+}
+
+// Validate returns every problem that would prevent the instance agent from deploying the
+// configuration of the service, joined with errors.Join. It returns nil for valid configurations.
+func (c ServiceConfiguration) Validate(nameID string) error {
+	var errs []error
+
+	if strings.TrimSpace(c.Image) == "" {
+		errs = append(errs, errors.New("image is required"))
+	}
+	if c.MinMem <= 0 {
+		errs = append(errs, errors.New("minMem must be positive"))
+	}
+	if c.MinMem > c.RecMem {
+		errs = append(errs, fmt.Errorf("minMem %d is greater than recMem %d", c.MinMem, c.RecMem))
+	}
+
+	keys := make(map[string]bool, len(c.Env))
+	for _, env := range c.Env {
+		if !envKeyPattern.MatchString(env.Key) {
+			errs = append(errs, fmt.Errorf("invalid env key: %q", env.Key))
+		}
+		if keys[env.Key] {
+			errs = append(errs, fmt.Errorf("duplicate env key: %s", env.Key))
+		}
+		keys[env.Key] = true
+
+		switch env.Type {
+		case "", EnvTypeString, EnvTypeNumber, EnvTypeBoolean:
+		default:
+			errs = append(errs, fmt.Errorf("invalid type of env key %s: %q", env.Key, env.Type))
+		}
+		for _, value := range env.Values {
+			if !env.allows(value.Value) {
+				errs = append(errs, fmt.Errorf("value %q of env key %s does not match its type", value.Value, env.Key))
+			}
+		}
+
+		if env.Default != "" && !env.allows(env.Default) {
+			errs = append(errs, fmt.Errorf("default %q of env key %s is not allowed", env.Default, env.Key))
+		}
+	}
+
+	hostPorts := make(map[string]bool, len(c.Ports))
+	for _, port := range c.Ports {
+		if port.Host < 1 || port.Host > 65535 || port.Container < 1 || port.Container > 65535 {
+			errs = append(errs, fmt.Errorf("port out of range: %d:%d", port.Host, port.Container))
+		}
+		if port.Protocol != "tcp" && port.Protocol != "udp" {
+			errs = append(errs, fmt.Errorf("invalid protocol for port %d: %q", port.Host, port.Protocol))
+		}
+
+		hostPort := fmt.Sprintf("%d/%s", port.Host, port.Protocol)
+		if hostPorts[hostPort] {
+			errs = append(errs, fmt.Errorf("duplicate host port: %s", hostPort))
+		}
+		hostPorts[hostPort] = true
+	}
+
+	root := VolumeRoot(nameID)
+	for _, volume := range c.Volumes {
+		if path.Clean(volume.Host) != volume.Host || !strings.HasPrefix(volume.Host, root+"/") {
+			errs = append(errs, fmt.Errorf("host volume path %q is outside %s", volume.Host, root))
+		}
+		if !path.IsAbs(volume.Destination) {
+			errs = append(errs, fmt.Errorf("volume destination %q is not absolute", volume.Destination))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Lint returns problems that do not prevent a deployment but degrade how the
+// service is presented to users
+func (c ServiceConfiguration) Lint() []string {
+	var warnings []string
+
+	if c.Name == "" || c.NameLong == "" {
+		warnings = append(warnings, "name and nameLong should be set")
+	}
+	if len(c.Ports) == 0 {
+		warnings = append(warnings, "no ports are exposed")
+	}
+	for _, env := range c.Env {
+		if env.Name == "" || env.Description == "" {
+			warnings = append(warnings, fmt.Sprintf("env key %s should have a name and description", env.Key))
+		}
+		if env.Required && env.Default != "" {
+			warnings = append(warnings, fmt.Sprintf("env key %s is required but has a default", env.Key))
+		}
+	}
+
+	return warnings
+}
+
+// ValidationErrors lists the problems joined in an error returned by Validate
+func ValidationErrors(err error) []string {
+	messages := []string{}
+	if err == nil {
+		return messages
+	}
+
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			messages = append(messages, e.Error())
+		}
+		return messages
+	}
+	return append(messages, err.Error())
+}
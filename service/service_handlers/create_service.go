@@ -0,0 +1,45 @@
+package service_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+	"github.com/mooncorn/gshub-main-api/service/service_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for creating a service
+type CreateServiceRequestBody struct {
+	NameID string                               `json:"nameId" binding:"required"`
+	Config service_presets.ServiceConfiguration `json:"config" binding:"required"`
+}
+
+// CreateService adds a service with its configuration as a draft preset.
+// The service cannot be used for instances until a preset version is published.
+func CreateService(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	var request CreateServiceRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	service := service_models.Service{NameID: request.NameID}
+	preset := service_models.ServicePreset{
+		Config:    request.Config,
+		CreatedBy: userEmail,
+	}
+
+	if err := appCtx.ServiceRepository.CreateService(&service, &preset); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create service", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"service": service,
+		"preset":  preset,
+	})
+}
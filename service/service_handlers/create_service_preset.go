@@ -0,0 +1,46 @@
+package service_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/service/service_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// CreateServicePreset adds a draft with the next version number of the service
+func CreateServicePreset(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	serviceID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service id", err, userEmail)
+		return
+	}
+
+	var request ServicePresetRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	if _, err := appCtx.ServiceRepository.GetService(uint(serviceID64)); err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Service not found", err, userEmail)
+		return
+	}
+
+	preset := service_models.ServicePreset{
+		ServiceID: uint(serviceID64),
+		Config:    request.Config,
+		CreatedBy: userEmail,
+	}
+
+	if err := appCtx.ServiceRepository.CreateServicePreset(&preset); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create preset", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, preset)
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/mooncorn/gshub-core/utils"
 	"github.com/mooncorn/gshub-main-api/app"
-	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
 )
 
 func GetService(c *gin.Context, appCtx *app.Context) {
@@ -25,7 +24,7 @@ func GetService(c *gin.Context, appCtx *app.Context) {
 		return
 	}
 
-	config, err := service_presets.GetServiceConfiguration(service.NameID)
+	preset, err := appCtx.ServiceRepository.GetPublishedPreset(service.ID)
 	if err != nil {
 		utils.HandleError(c, http.StatusInternalServerError, "Cannot get service config", err, "null")
 		return
@@ -33,6 +32,7 @@ func GetService(c *gin.Context, appCtx *app.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"service": service,
-		"config":  config,
+		"config":  preset.Config,
+		"version": preset.Version,
 	})
 }
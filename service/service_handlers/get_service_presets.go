@@ -0,0 +1,38 @@
+package service_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// GetServicePresets returns every preset version of the service, newest first
+func GetServicePresets(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	serviceID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service id", err, userEmail)
+		return
+	}
+
+	service, err := appCtx.ServiceRepository.GetService(uint(serviceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Service not found", err, userEmail)
+		return
+	}
+
+	presets, err := appCtx.ServiceRepository.GetServicePresets(service.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get presets", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service": service,
+		"presets": presets,
+	})
+}
@@ -0,0 +1,23 @@
+package service_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/service/service_lint"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// LintServicePresets validates the seed file and the draft and published presets of every service
+func LintServicePresets(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	results, err := service_lint.LintPresets(appCtx.ServiceRepository)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to lint presets", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,42 @@
+package service_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+	"github.com/mooncorn/gshub-main-api/service/service_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// PublishServicePreset makes a valid draft the version used by new instances.
+// Existing instances keep the version they were created with.
+func PublishServicePreset(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	service, preset, ok := getPresetFromParams(c, appCtx, userEmail)
+	if !ok {
+		return
+	}
+
+	if err := preset.Config.Validate(service.NameID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid preset",
+			"errors": service_presets.ValidationErrors(err),
+		})
+		return
+	}
+
+	if err := appCtx.ServiceRepository.PublishPreset(preset); err != nil {
+		if errors.Is(err, service_repositories.ErrPresetNotDraft) {
+			utils.HandleError(c, http.StatusConflict, "Only drafts can be published", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to publish preset", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, preset)
+}
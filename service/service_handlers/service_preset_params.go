@@ -0,0 +1,47 @@
+package service_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+	"github.com/mooncorn/gshub-main-api/service/service_models"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for creating or editing a preset version
+type ServicePresetRequestBody struct {
+	Config service_presets.ServiceConfiguration `json:"config" binding:"required"`
+}
+
+// getPresetFromParams loads the service and preset version identified by the :id and :version
+// route params. The error response is written when false is returned.
+func getPresetFromParams(c *gin.Context, appCtx *app.Context, userEmail string) (*service_models.Service, *service_models.ServicePreset, bool) {
+	serviceID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid service id", err, userEmail)
+		return nil, nil, false
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid preset version", err, userEmail)
+		return nil, nil, false
+	}
+
+	service, err := appCtx.ServiceRepository.GetService(uint(serviceID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Service not found", err, userEmail)
+		return nil, nil, false
+	}
+
+	preset, err := appCtx.ServiceRepository.GetServicePreset(service.ID, version)
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Preset not found", err, userEmail)
+		return nil, nil, false
+	}
+
+	return service, preset, true
+}
@@ -0,0 +1,38 @@
+package service_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/service/service_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// UpdateServicePreset replaces the configuration of a draft preset version
+func UpdateServicePreset(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	var request ServicePresetRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	_, preset, ok := getPresetFromParams(c, appCtx, userEmail)
+	if !ok {
+		return
+	}
+
+	if err := appCtx.ServiceRepository.UpdateDraftPreset(preset, request.Config); err != nil {
+		if errors.Is(err, service_repositories.ErrPresetNotDraft) {
+			utils.HandleError(c, http.StatusConflict, "Only drafts can be edited", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to save preset", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, preset)
+}
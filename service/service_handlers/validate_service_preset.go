@@ -0,0 +1,31 @@
+package service_handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+)
+
+// ValidateServicePreset reports the problems that would prevent publishing the preset version
+func ValidateServicePreset(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	service, preset, ok := getPresetFromParams(c, appCtx, userEmail)
+	if !ok {
+		return
+	}
+
+	errs := service_presets.ValidationErrors(preset.Config.Validate(service.NameID))
+	warnings := preset.Config.Lint()
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    len(errs) == 0,
+		"errors":   errs,
+		"warnings": warnings,
+	})
+}
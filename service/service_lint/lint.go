@@ -0,0 +1,69 @@
+package service_lint
+
+import (
+	"fmt"
+
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+	"github.com/mooncorn/gshub-main-api/service/service_models"
+	"github.com/mooncorn/gshub-main-api/service/service_repositories"
+)
+
+// PresetLint is the result of validating a single preset
+type PresetLint struct {
+	NameID   string   `json:"nameId"`
+	Source   string   `json:"source"` // "seed" or the preset version, e.g. "v2 (draft)"
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// LintPresets validates the seed file and every draft and published preset in the database.
+// Archived versions are skipped since they cannot be used by new instances.
+func LintPresets(repo *service_repositories.ServiceRepository) ([]PresetLint, error) {
+	results := []PresetLint{}
+
+	configs, err := service_presets.GetServiceConfigurations()
+	if err != nil {
+		return nil, err
+	}
+	for nameID, config := range configs {
+		results = append(results, lint(nameID, "seed", config))
+	}
+
+	services, err := repo.GetServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, service := range *services {
+		presets, err := repo.GetServicePresets(service.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, preset := range *presets {
+			if preset.Status == service_models.ServicePresetStatusArchived {
+				continue
+			}
+			source := fmt.Sprintf("v%d (%s)", preset.Version, preset.Status)
+			results = append(results, lint(service.NameID, source, preset.Config))
+		}
+	}
+
+	return results, nil
+}
+
+func lint(nameID string, source string, config service_presets.ServiceConfiguration) PresetLint {
+	errs := service_presets.ValidationErrors(config.Validate(nameID))
+	warnings := config.Lint()
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	return PresetLint{
+		NameID:   nameID,
+		Source:   source,
+		Valid:    len(errs) == 0,
+		Errors:   errs,
+		Warnings: warnings,
+	}
+}
@@ -13,6 +13,6 @@ type Service struct {
 	UpdatedAt time.Time      `json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt,omitempty"`
 
-	// Service NameID is used to identify service configuration preset
+	// Service NameID identifies the service, the seed file keys its presets by it
 	NameID string `gorm:"uniqueIndex" json:"nameId"`
 }
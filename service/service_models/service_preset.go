@@ -0,0 +1,29 @@
+package service_models
+
+import (
+	"time"
+
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
+)
+
+type ServicePresetStatus string
+
+// Only drafts can be edited, a service has at most one published version
+const (
+	ServicePresetStatusDraft     ServicePresetStatus = "draft"
+	ServicePresetStatusPublished ServicePresetStatus = "published"
+	ServicePresetStatusArchived  ServicePresetStatus = "archived"
+)
+
+// ServicePreset is a version of the configuration used to deploy a service
+type ServicePreset struct {
+	ID          uint                                 `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time                            `json:"createdAt"`
+	UpdatedAt   time.Time                            `json:"updatedAt"`
+	ServiceID   uint                                 `gorm:"not null;uniqueIndex:idx_service_preset_version" json:"serviceId"`
+	Version     int                                  `gorm:"not null;uniqueIndex:idx_service_preset_version" json:"version"`
+	Status      ServicePresetStatus                  `gorm:"not null;index" json:"status"`
+	Config      service_presets.ServiceConfiguration `gorm:"serializer:json;not null" json:"config"`
+	CreatedBy   string                               `gorm:"not null" json:"createdBy"`
+	PublishedAt *time.Time                           `json:"publishedAt"`
+}
@@ -1,10 +1,19 @@
 package service_repositories
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	service_presets "github.com/mooncorn/gshub-main-api/service/presets"
 	"github.com/mooncorn/gshub-main-api/service/service_models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrPresetNotDraft = errors.New("preset version is not a draft")
+
 type ServiceRepository struct {
 	DB *gorm.DB
 }
@@ -24,3 +33,166 @@ func (r *ServiceRepository) GetServices() (*[]service_models.Service, error) {
 	err := r.DB.Find(&services).Error
 	return &services, err
 }
+
+// CreateService creates the service with its first draft preset
+func (r *ServiceRepository) CreateService(service *service_models.Service, preset *service_models.ServicePreset) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(service).Error; err != nil {
+			return err
+		}
+
+		preset.ServiceID = service.ID
+		preset.Version = 1
+		preset.Status = service_models.ServicePresetStatusDraft
+		return tx.Create(preset).Error
+	})
+}
+
+func (r *ServiceRepository) GetServicePresets(serviceID uint) (*[]service_models.ServicePreset, error) {
+	var presets []service_models.ServicePreset
+	err := r.DB.Where("service_id = ?", serviceID).Order("version desc").Find(&presets).Error
+	return &presets, err
+}
+
+func (r *ServiceRepository) GetServicePreset(serviceID uint, version int) (*service_models.ServicePreset, error) {
+	var preset service_models.ServicePreset
+	err := r.DB.Where("service_id = ? AND version = ?", serviceID, version).First(&preset).Error
+	return &preset, err
+}
+
+// GetPublishedPreset returns the preset version used for new instances of the service
+func (r *ServiceRepository) GetPublishedPreset(serviceID uint) (*service_models.ServicePreset, error) {
+	var preset service_models.ServicePreset
+	err := r.DB.Where("service_id = ? AND status = ?", serviceID, service_models.ServicePresetStatusPublished).First(&preset).Error
+	return &preset, err
+}
+
+// GetInstancePreset returns the preset an instance was created with, instances
+// created before presets were versioned (presetID 0) use the published one
+func (r *ServiceRepository) GetInstancePreset(serviceID uint, presetID uint) (*service_models.ServicePreset, error) {
+	if presetID == 0 {
+		return r.GetPublishedPreset(serviceID)
+	}
+
+	var preset service_models.ServicePreset
+	err := r.DB.Where("service_id = ?", serviceID).First(&preset, presetID).Error
+	return &preset, err
+}
+
+// CreateServicePreset adds a draft with the next version number of the service
+func (r *ServiceRepository) CreateServicePreset(preset *service_models.ServicePreset) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize version numbering per service
+		var service service_models.Service
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&service, preset.ServiceID).Error; err != nil {
+			return err
+		}
+
+		var latest int
+		if err := tx.Model(&service_models.ServicePreset{}).
+			Where("service_id = ?", preset.ServiceID).
+			Select("COALESCE(MAX(version), 0)").
+			Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		preset.Version = latest + 1
+		preset.Status = service_models.ServicePresetStatusDraft
+		return tx.Create(preset).Error
+	})
+}
+
+// UpdateDraftPreset replaces the configuration of a draft
+func (r *ServiceRepository) UpdateDraftPreset(preset *service_models.ServicePreset, config service_presets.ServiceConfiguration) error {
+	previous := preset.Config
+	preset.Config = config
+
+	result := r.DB.Model(preset).
+		Where("status = ?", service_models.ServicePresetStatusDraft).
+		Select("config").
+		Updates(preset)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrPresetNotDraft
+	}
+	if result.Error != nil {
+		preset.Config = previous
+	}
+	return result.Error
+}
+
+// PublishPreset makes the draft the published version and archives the previous one.
+// Instances keep the version they were created with.
+func (r *ServiceRepository) PublishPreset(preset *service_models.ServicePreset) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var service service_models.Service
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&service, preset.ServiceID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&service_models.ServicePreset{}).
+			Where("service_id = ? AND status = ?", preset.ServiceID, service_models.ServicePresetStatusPublished).
+			Update("status", service_models.ServicePresetStatusArchived).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		result := tx.Model(preset).
+			Where("status = ?", service_models.ServicePresetStatusDraft).
+			Updates(map[string]interface{}{
+				"status":       service_models.ServicePresetStatusPublished,
+				"published_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPresetNotDraft
+		}
+
+		preset.Status = service_models.ServicePresetStatusPublished
+		preset.PublishedAt = &now
+		return nil
+	})
+}
+
+// SeedServicePresets publishes the valid configurations of the seed file as version 1 of
+// services that have no preset yet, creating the services if needed. It is safe to run
+// on every start.
+func (r *ServiceRepository) SeedServicePresets(configs map[string]service_presets.ServiceConfiguration) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for nameID, config := range configs {
+			// Invalid entries are skipped instead of failing the start
+			if err := config.Validate(nameID); err != nil {
+				log.Printf("Warning: skipping invalid preset for %s in seed file: %v", nameID, err)
+				continue
+			}
+
+			var service service_models.Service
+			if err := tx.Where(service_models.Service{NameID: nameID}).FirstOrCreate(&service).Error; err != nil {
+				return fmt.Errorf("failed to get service %s: %v", nameID, err)
+			}
+
+			var count int64
+			if err := tx.Model(&service_models.ServicePreset{}).Where("service_id = ?", service.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			now := time.Now()
+			preset := service_models.ServicePreset{
+				ServiceID:   service.ID,
+				Version:     1,
+				Status:      service_models.ServicePresetStatusPublished,
+				Config:      config,
+				CreatedBy:   "seed",
+				PublishedAt: &now,
+			}
+			if err := tx.Create(&preset).Error; err != nil {
+				return fmt.Errorf("failed to seed preset for %s: %v", nameID, err)
+			}
+		}
+		return nil
+	})
+}
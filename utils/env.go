@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// GetDurationEnv reads a duration such as "5m" from the environment, falling back to def
+func GetDurationEnv(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration in %s: %v, using %s", key, err, def)
+		return def
+	}
+	return duration
+}
@@ -0,0 +1,67 @@
+package wallet_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// The payload for contributing cycles to a wallet
+type ContributeToWalletRequestBody struct {
+	Amount uint `json:"amount" binding:"required"`
+}
+
+// ContributeToWallet moves cycles from the user's balance into a wallet they are a member of
+func ContributeToWallet(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	walletID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid wallet id", err, userEmail)
+		return
+	}
+
+	var request ContributeToWalletRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet, err := appCtx.WalletRepository.GetMemberWallet(user.ID, uint(walletID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Wallet not found", err, userEmail)
+		return
+	}
+
+	transaction, err := appCtx.LedgerRepository.Transfer(ledger_repositories.Transfer{
+		From:        ledger_repositories.UserAccount(user.ID),
+		To:          ledger_repositories.WalletAccount(wallet.ID),
+		Amount:      request.Amount,
+		Reason:      ledger_models.ReasonContribution,
+		Description: "Contribution to " + wallet.Name,
+		Actor:       userEmail,
+		UserID:      user.ID,
+	})
+	if err != nil {
+		if errors.Is(err, ledger_repositories.ErrInsufficientBalance) {
+			utils.HandleError(c, http.StatusBadRequest, "Not enough cycles", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to contribute cycles", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
@@ -0,0 +1,44 @@
+package wallet_handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_models"
+)
+
+// The payload for creating a wallet
+type CreateWalletRequestBody struct {
+	Name string `json:"name" binding:"required,max=64"`
+}
+
+// CreateWallet creates a wallet owned by the user
+func CreateWallet(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	var request CreateWalletRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet := wallet_models.Wallet{
+		Name:    strings.TrimSpace(request.Name),
+		OwnerID: user.ID,
+	}
+	if err := appCtx.WalletRepository.CreateWallet(&wallet); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to create wallet", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusCreated, wallet)
+}
@@ -0,0 +1,108 @@
+package wallet_handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_models"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+)
+
+// WalletMemberReport is what a user put into a wallet and what their instances burned from it
+type WalletMemberReport struct {
+	UserID      uint   `json:"userId"`
+	Email       string `json:"email"`
+	Member      bool   `json:"member"` // False for users who left the wallet
+	Contributed int64  `json:"contributed"`
+	Burned      int64  `json:"burned"`
+	Net         int64  `json:"net"` // Contributed minus burned, negative when others paid for the user's running time
+}
+
+// GetWalletReport returns the contributions and burns of every user of a wallet so members can settle up
+func GetWalletReport(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	walletID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid wallet id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet, err := appCtx.WalletRepository.GetMemberWallet(user.ID, uint(walletID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Wallet not found", err, userEmail)
+		return
+	}
+
+	account := ledger_repositories.WalletAccount(wallet.ID)
+
+	contributed, err := appCtx.LedgerRepository.GetCreditsByUser(account, ledger_models.ReasonContribution)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get contributions", err, userEmail)
+		return
+	}
+
+	burned, err := appCtx.LedgerRepository.GetDebitsByUser(account, ledger_models.ReasonBurn, ledger_models.ReasonFailedStartupBurn)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get burns", err, userEmail)
+		return
+	}
+
+	balance, err := appCtx.LedgerRepository.GetBalance(account)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get balance", err, userEmail)
+		return
+	}
+
+	// Current members first, then users who left but still appear in the ledger
+	reports := []WalletMemberReport{}
+	reported := make(map[uint]bool)
+	for _, member := range wallet.Members {
+		reports = append(reports, WalletMemberReport{UserID: member.UserID, Email: member.Email, Member: true})
+		reported[member.UserID] = true
+	}
+	var formerIDs []uint
+	for _, totals := range []map[uint]int64{contributed, burned} {
+		for userID := range totals {
+			if !reported[userID] {
+				formerIDs = append(formerIDs, userID)
+				reported[userID] = true
+			}
+		}
+	}
+	sort.Slice(formerIDs, func(i, j int) bool { return formerIDs[i] < formerIDs[j] })
+	for _, userID := range formerIDs {
+		report := WalletMemberReport{UserID: userID}
+		if former, err := appCtx.UserRepository.GetUser(userID); err == nil {
+			report.Email = former.Email
+		}
+		reports = append(reports, report)
+	}
+
+	var totalContributed, totalBurned int64
+	for i := range reports {
+		reports[i].Contributed = contributed[reports[i].UserID]
+		reports[i].Burned = burned[reports[i].UserID]
+		reports[i].Net = reports[i].Contributed - reports[i].Burned
+		totalContributed += reports[i].Contributed
+		totalBurned += reports[i].Burned
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"walletId":         wallet.ID,
+		"balance":          balance,
+		"totalContributed": totalContributed,
+		"totalBurned":      totalBurned,
+		"members":          reports,
+	})
+}
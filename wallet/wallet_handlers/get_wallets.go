@@ -0,0 +1,78 @@
+package wallet_handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/ledger/ledger_repositories"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_models"
+)
+
+// WalletWithBalance is a wallet together with the cycles it holds
+type WalletWithBalance struct {
+	wallet_models.Wallet
+	Balance int64 `json:"balance"`
+}
+
+// GetWallets returns the wallets the user is a member of with their balances
+func GetWallets(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallets, err := appCtx.WalletRepository.GetUserWallets(user.ID)
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get wallets", err, userEmail)
+		return
+	}
+
+	response := make([]WalletWithBalance, 0, len(*wallets))
+	for _, wallet := range *wallets {
+		balance, err := appCtx.LedgerRepository.GetBalance(ledger_repositories.WalletAccount(wallet.ID))
+		if err != nil {
+			utils.HandleError(c, http.StatusInternalServerError, "Failed to get balance", err, userEmail)
+			return
+		}
+		response = append(response, WalletWithBalance{Wallet: wallet, Balance: balance})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetWallet returns a wallet of the user with its members and balance
+func GetWallet(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	walletID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid wallet id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet, err := appCtx.WalletRepository.GetMemberWallet(user.ID, uint(walletID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Wallet not found", err, userEmail)
+		return
+	}
+
+	balance, err := appCtx.LedgerRepository.GetBalance(ledger_repositories.WalletAccount(wallet.ID))
+	if err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to get balance", err, userEmail)
+		return
+	}
+
+	c.JSON(http.StatusOK, WalletWithBalance{Wallet: *wallet, Balance: balance})
+}
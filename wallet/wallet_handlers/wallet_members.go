@@ -0,0 +1,107 @@
+package wallet_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mooncorn/gshub-main-api/app"
+	"github.com/mooncorn/gshub-main-api/utils"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_repositories"
+)
+
+// The payload for adding a member to a wallet
+type AddWalletMemberRequestBody struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// AddWalletMember adds a registered user to the wallet of the owner
+func AddWalletMember(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	walletID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid wallet id", err, userEmail)
+		return
+	}
+
+	var request AddWalletMemberRequestBody
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, utils.ErrorMessage{Error: "Invalid request"})
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet, err := appCtx.WalletRepository.GetMemberWallet(user.ID, uint(walletID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Wallet not found", err, userEmail)
+		return
+	}
+	if wallet.OwnerID != user.ID {
+		utils.HandleError(c, http.StatusForbidden, "Only the owner adds members", errors.New("not the wallet owner"), userEmail)
+		return
+	}
+
+	member, err := appCtx.UserRepository.GetUserByEmail(request.Email)
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "User not found", err, userEmail)
+		return
+	}
+
+	if err := appCtx.WalletRepository.AddWalletMember(wallet, member); err != nil {
+		utils.HandleError(c, http.StatusInternalServerError, "Failed to add member", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RemoveWalletMember removes a member from the wallet. The owner removes anyone, members only remove themselves.
+func RemoveWalletMember(c *gin.Context, appCtx *app.Context) {
+	userEmail := c.GetString("userEmail")
+
+	walletID64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid wallet id", err, userEmail)
+		return
+	}
+
+	memberID64, err := strconv.ParseUint(c.Param("userId"), 10, 32)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user id", err, userEmail)
+		return
+	}
+
+	user, err := appCtx.UserRepository.GetUserByEmail(userEmail)
+	if err != nil {
+		utils.HandleError(c, http.StatusBadRequest, "Invalid user", err, userEmail)
+		return
+	}
+
+	wallet, err := appCtx.WalletRepository.GetMemberWallet(user.ID, uint(walletID64))
+	if err != nil {
+		utils.HandleError(c, http.StatusNotFound, "Wallet not found", err, userEmail)
+		return
+	}
+	if wallet.OwnerID != user.ID && uint(memberID64) != user.ID {
+		utils.HandleError(c, http.StatusForbidden, "Only the owner removes other members", errors.New("not the wallet owner"), userEmail)
+		return
+	}
+
+	if err := appCtx.WalletRepository.RemoveWalletMember(wallet, uint(memberID64)); err != nil {
+		if errors.Is(err, wallet_repositories.ErrWalletOwner) {
+			utils.HandleError(c, http.StatusBadRequest, "The owner cannot leave the wallet", err, userEmail)
+			return
+		}
+		utils.HandleError(c, http.StatusNotFound, "Member not found", err, userEmail)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,25 @@
+package wallet_models
+
+import (
+	"time"
+)
+
+// Wallet pools the cycles of its members, instances of the members can burn from it.
+// The cycles are held by the wallet's ledger account.
+type Wallet struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+	Name      string         `gorm:"not null" json:"name"`
+	OwnerID   uint           `gorm:"not null;index" json:"ownerId"` // User managing the members, a member too
+	Members   []WalletMember `json:"members,omitempty"`
+}
+
+// WalletMember lets a user contribute to the wallet and fund their instances from it
+type WalletMember struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	WalletID  uint      `gorm:"not null;uniqueIndex:idx_wallet_member" json:"walletId"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_wallet_member;index" json:"userId"`
+	Email     string    `gorm:"-" json:"email"`
+}
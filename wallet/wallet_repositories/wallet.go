@@ -0,0 +1,115 @@
+package wallet_repositories
+
+import (
+	"errors"
+
+	"github.com/mooncorn/gshub-main-api/instance/instance_models"
+	"github.com/mooncorn/gshub-main-api/user/user_models"
+	"github.com/mooncorn/gshub-main-api/wallet/wallet_models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrWalletOwner = errors.New("the owner cannot leave the wallet")
+
+type WalletRepository struct {
+	DB *gorm.DB
+}
+
+func NewWalletRepository(db *gorm.DB) *WalletRepository {
+	return &WalletRepository{DB: db}
+}
+
+// CreateWallet creates the wallet with its owner as the first member
+func (r *WalletRepository) CreateWallet(wallet *wallet_models.Wallet) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(wallet).Error; err != nil {
+			return err
+		}
+
+		member := wallet_models.WalletMember{WalletID: wallet.ID, UserID: wallet.OwnerID}
+		if err := tx.Create(&member).Error; err != nil {
+			return err
+		}
+
+		wallet.Members = []wallet_models.WalletMember{member}
+		return nil
+	})
+}
+
+// GetUserWallets returns the wallets the user is a member of
+func (r *WalletRepository) GetUserWallets(userID uint) (*[]wallet_models.Wallet, error) {
+	var wallets []wallet_models.Wallet
+	err := r.DB.
+		Where("id IN (SELECT wallet_id FROM wallet_members WHERE user_id = ?)", userID).
+		Order("created_at").
+		Find(&wallets).Error
+	return &wallets, err
+}
+
+// GetMemberWallet returns the wallet with its members if the user is one of them,
+// gorm.ErrRecordNotFound otherwise
+func (r *WalletRepository) GetMemberWallet(userID uint, walletID uint) (*wallet_models.Wallet, error) {
+	var wallet wallet_models.Wallet
+	err := r.DB.
+		Where("id = ? AND id IN (SELECT wallet_id FROM wallet_members WHERE user_id = ?)", walletID, userID).
+		First(&wallet).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var members []struct {
+		wallet_models.WalletMember
+		UserEmail string
+	}
+	err = r.DB.Model(&wallet_models.WalletMember{}).
+		Select("wallet_members.*, users.email AS user_email").
+		Joins("JOIN users ON users.id = wallet_members.user_id").
+		Where("wallet_members.wallet_id = ?", wallet.ID).
+		Order("wallet_members.created_at").
+		Scan(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		member.WalletMember.Email = member.UserEmail
+		wallet.Members = append(wallet.Members, member.WalletMember)
+	}
+	return &wallet, nil
+}
+
+// AddWalletMember adds the user to the wallet, adding a member again does nothing
+func (r *WalletRepository) AddWalletMember(wallet *wallet_models.Wallet, user *user_models.User) error {
+	member := wallet_models.WalletMember{WalletID: wallet.ID, UserID: user.ID}
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error
+}
+
+// RemoveWalletMember removes the user from the wallet. The user's instances funded by the
+// wallet go back to their own balance. Contributions and burns stay in the ledger.
+func (r *WalletRepository) RemoveWalletMember(wallet *wallet_models.Wallet, userID uint) error {
+	if userID == wallet.OwnerID {
+		return ErrWalletOwner
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("wallet_id = ? AND user_id = ?", wallet.ID, userID).Delete(&wallet_models.WalletMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return tx.Model(&instance_models.Instance{}).
+			Where("wallet_id = ? AND user_id = ?", wallet.ID, userID).
+			Update("wallet_id", nil).Error
+	})
+}
+
+// IsWalletMember reports whether the user is a member of the wallet
+func (r *WalletRepository) IsWalletMember(walletID uint, userID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&wallet_models.WalletMember{}).Where("wallet_id = ? AND user_id = ?", walletID, userID).Count(&count).Error
+	return count > 0, err
+}